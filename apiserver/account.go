@@ -0,0 +1,205 @@
+package apiserver
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/store"
+)
+
+type DeleteMeRequest struct {
+	Password string `json:"password"`
+}
+
+func (r DeleteMeRequest) Validate() error {
+	if r.Password == "" {
+		return errors.New("password is required")
+	}
+	return nil
+}
+
+// deleteMeHandler deletes the current user and everything they own. The
+// output files of their reports are deleted from blob storage in the
+// background by purgeBlobs.
+func (s *APIServer) deleteMeHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[DeleteMeRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		user := currentUser(c)
+		if err := s.verifyPassword(c, user, req.Password); err != nil {
+			if e, ok := err.(*ErrWithStatus); ok && e.status == fiber.StatusBadRequest {
+				s.audit(c, failedUserEvent(dto.AuditAccountDelete, user.ID, "invalid_password"))
+			}
+			return err
+		}
+
+		// Organizations the user is the only member of are deleted with them,
+		// but others must not be left without an admin.
+		if _, err := s.store.Users.Delete(c.UserContext(), user.ID); err != nil {
+			if errors.Is(err, store.ErrLastAdmin) {
+				return NewErrWithStatus(fiber.StatusConflict, errors.New("make another member admin of your organizations before deleting your account"))
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, userEvent(dto.AuditAccountDelete, user.ID))
+		if err := s.revokeUserSessions(c.UserContext(), user.ID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully deleted account"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// exportMeHandler sends a ZIP archive of the current user's personal data:
+// their profile, organizations and reports, with the output file of each
+// report. The archive is built in a temporary file before the response is
+// sent, so that a failure to export any part of it fails the request instead
+// of sending an incomplete archive.
+func (s *APIServer) exportMeHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user := currentUser(c)
+		orgs, err := s.store.Organizations.ByUserID(c.UserContext(), user.ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		reports, err := s.store.Reports.ByUserID(c.UserContext(), user.ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if s.blobs == nil && slices.ContainsFunc(reports, func(report dto.Report) bool { return report.OutputFilePath != nil }) {
+			return NewErrWithStatus(fiber.StatusServiceUnavailable, errors.New("blob storage is not configured"))
+		}
+
+		orgsResp := make([]OrganizationResponse, 0, len(orgs))
+		for _, org := range orgs {
+			orgsResp = append(orgsResp, OrganizationResponse{ID: org.ID, Name: org.Name, Role: org.Role, CreatedAt: org.CreatedAt})
+		}
+		reportsResp := make([]ReportResponse, 0, len(reports))
+		for i := range reports {
+			reportsResp = append(reportsResp, newReportResponse(&reports[i]))
+		}
+		files := []exportFile{
+			{"profile.json", newUserResponse(user)},
+			{"organizations.json", orgsResp},
+			{"reports.json", reportsResp},
+		}
+
+		f, err := os.CreateTemp("", "export-*.zip")
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, fmt.Errorf("failed to create personal data export: %w", err))
+		}
+		// The open file outlives its name, and is closed once it has been sent.
+		if err := os.Remove(f.Name()); err != nil {
+			f.Close()
+			return NewErrWithStatus(fiber.StatusInternalServerError, fmt.Errorf("failed to create personal data export: %w", err))
+		}
+
+		size, err := s.writeExport(c.UserContext(), f, files, reports)
+		if err != nil {
+			f.Close()
+			return NewErrWithStatus(fiber.StatusInternalServerError, fmt.Errorf("failed to export personal data: %w", err))
+		}
+		s.audit(c, userEvent(dto.AuditAccountExport, user.ID))
+
+		c.Set(fiber.HeaderContentType, "application/zip")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="export.zip"`)
+		return c.Status(fiber.StatusOK).SendStream(f, int(size))
+	})
+}
+
+type exportFile struct {
+	name string
+	data any
+}
+
+// writeExport writes the archive of a personal data export to f and rewinds
+// it, returning its size.
+func (s *APIServer) writeExport(ctx context.Context, f *os.File, files []exportFile, reports []dto.Report) (int64, error) {
+	archive := zip.NewWriter(f)
+	for _, file := range files {
+		if err := writeJSONFile(archive, file.name, file.data); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	for _, report := range reports {
+		if report.OutputFilePath == nil {
+			continue
+		}
+		name := path.Join("reports", report.ID.String(), path.Base(*report.OutputFilePath))
+		if err := s.exportBlob(ctx, archive, name, *report.OutputFilePath); err != nil {
+			return 0, fmt.Errorf("failed to write output of report %s: %w", report.ID, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func writeJSONFile(archive *zip.Writer, name string, data any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+func (s *APIServer) exportBlob(ctx context.Context, archive *zip.Writer, name, key string) error {
+	blob, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, blob)
+	return err
+}
+
+// purgeBlobs deletes the blobs queued for deletion from blob storage. Blobs
+// that fail to delete stay queued for the next run.
+func (s *APIServer) purgeBlobs(ctx context.Context) error {
+	paths, err := s.store.BlobDeletions.Pending(ctx, 100)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, key := range paths {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := s.store.BlobDeletions.Done(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
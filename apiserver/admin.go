@@ -0,0 +1,364 @@
+package apiserver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/store"
+)
+
+const (
+	defaultAdminLimit = 50
+	maxAdminLimit     = 200
+)
+
+// pagination returns the limit and offset query parameters of an admin
+// listing.
+func pagination(c *fiber.Ctx) (int, int, error) {
+	limit := c.QueryInt("limit", defaultAdminLimit)
+	if limit < 1 || limit > maxAdminLimit {
+		return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxAdminLimit)
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		return 0, 0, errors.New("offset must not be negative")
+	}
+	return limit, offset, nil
+}
+
+// auditAdminAction records an action taken by an operator on behalf of a
+// user.
+func (s *APIServer) auditAdminAction(c *fiber.Ctx, eventType, targetType, targetID string) {
+	s.audit(c, dto.AuditEvent{
+		EventType:  eventType,
+		TargetType: targetType,
+		TargetID:   targetID,
+	})
+}
+
+type AdminUserResponse struct {
+	UserResponse
+	FailedSigninAttempts int        `json:"failed_signin_attempts"`
+	LockedUntil          *time.Time `json:"locked_until"`
+	DisabledAt           *time.Time `json:"disabled_at"`
+}
+
+func newAdminUserResponse(user *dto.User) AdminUserResponse {
+	return AdminUserResponse{
+		UserResponse:         newUserResponse(user),
+		FailedSigninAttempts: user.FailedSigninAttempts,
+		LockedUntil:          user.LockedUntil,
+		DisabledAt:           user.DisabledAt,
+	}
+}
+
+func (s *APIServer) adminListUsersHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		limit, offset, err := pagination(c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		users, err := s.store.Users.Search(c.UserContext(), c.Query("q"), limit, offset)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := make([]AdminUserResponse, 0, len(users))
+		for i := range users {
+			resp = append(resp, newAdminUserResponse(&users[i]))
+		}
+
+		if err := encode(APIResponse[[]AdminUserResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// adminUser loads the user named by the id route parameter.
+func (s *APIServer) adminUser(c *fiber.Ctx) (*dto.User, error) {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid user id: %w", err))
+	}
+
+	user, err := s.store.Users.ByID(c.UserContext(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NewErrWithStatus(fiber.StatusNotFound, err)
+		}
+		return nil, NewErrWithStatus(fiber.StatusInternalServerError, err)
+	}
+	return user, nil
+}
+
+func (s *APIServer) adminGetUserHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user, err := s.adminUser(c)
+		if err != nil {
+			return err
+		}
+
+		resp := newAdminUserResponse(user)
+		if err := encode(APIResponse[AdminUserResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// adminDisableUserHandler disables an account and signs it out everywhere.
+// API keys stay in place but are rejected while the account is disabled.
+func (s *APIServer) adminDisableUserHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user, err := s.adminUser(c)
+		if err != nil {
+			return err
+		}
+		if user.ID == currentUser(c).ID {
+			return NewErrWithStatus(fiber.StatusConflict, errors.New("you cannot disable your own account"))
+		}
+
+		user, err = s.store.Users.Disable(c.UserContext(), user.ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if err := s.revokeUserSessions(c.UserContext(), user.ID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.auditAdminAction(c, dto.AuditAdminDisableUser, dto.AuditTargetUser, user.ID.String())
+
+		resp := newAdminUserResponse(user)
+		if err := encode(APIResponse[AdminUserResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) adminEnableUserHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user, err := s.adminUser(c)
+		if err != nil {
+			return err
+		}
+
+		user, err = s.store.Users.Enable(c.UserContext(), user.ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.auditAdminAction(c, dto.AuditAdminEnableUser, dto.AuditTargetUser, user.ID.String())
+
+		resp := newAdminUserResponse(user)
+		if err := encode(APIResponse[AdminUserResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// adminUnlockUserHandler lifts a signin lockout before it expires.
+func (s *APIServer) adminUnlockUserHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user, err := s.adminUser(c)
+		if err != nil {
+			return err
+		}
+
+		user, err = s.store.Users.ResetFailedSignins(c.UserContext(), user.ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.auditAdminAction(c, dto.AuditAdminUnlockUser, dto.AuditTargetUser, user.ID.String())
+
+		resp := newAdminUserResponse(user)
+		if err := encode(APIResponse[AdminUserResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) adminSignoutUserHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user, err := s.adminUser(c)
+		if err != nil {
+			return err
+		}
+
+		if err := s.revokeUserSessions(c.UserContext(), user.ID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.auditAdminAction(c, dto.AuditAdminSignoutUser, dto.AuditTargetUser, user.ID.String())
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully signed out user"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+type AdminReportResponse struct {
+	ReportResponse
+	Status         string  `json:"status"`
+	OutputFilePath *string `json:"output_file_path"`
+}
+
+func newAdminReportResponse(report *dto.Report) AdminReportResponse {
+	return AdminReportResponse{
+		ReportResponse: newReportResponse(report),
+		Status:         report.Status(),
+		OutputFilePath: report.OutputFilePath,
+	}
+}
+
+// optionalUUIDQuery parses the query parameter key, if present.
+func optionalUUIDQuery(c *fiber.Ctx, key string) (*uuid.UUID, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return &id, nil
+}
+
+func (s *APIServer) adminListReportsHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		limit, offset, err := pagination(c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		filter := store.ReportFilter{
+			ReportType: c.Query("report_type"),
+			Status:     c.Query("status"),
+			Limit:      limit,
+			Offset:     offset,
+		}
+		if filter.Status != "" && !slices.Contains(dto.ReportStatuses, filter.Status) {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("unknown status %q", filter.Status))
+		}
+		if filter.UserID, err = optionalUUIDQuery(c, "user_id"); err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+		if filter.OrgID, err = optionalUUIDQuery(c, "org_id"); err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		reports, err := s.store.Reports.Search(c.UserContext(), filter)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := make([]AdminReportResponse, 0, len(reports))
+		for i := range reports {
+			resp = append(resp, newAdminReportResponse(&reports[i]))
+		}
+
+		if err := encode(APIResponse[[]AdminReportResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// adminReport loads the report named by the id route parameter.
+func (s *APIServer) adminReport(c *fiber.Ctx) (*dto.Report, error) {
+	reportID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid report id: %w", err))
+	}
+
+	report, err := s.store.Reports.ByID(c.UserContext(), reportID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NewErrWithStatus(fiber.StatusNotFound, err)
+		}
+		return nil, NewErrWithStatus(fiber.StatusInternalServerError, err)
+	}
+	return report, nil
+}
+
+// adminRequeueReportHandler resets a completed or failed report to pending,
+// discarding its previous outcome, so that it is generated again.
+func (s *APIServer) adminRequeueReportHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		report, err := s.adminReport(c)
+		if err != nil {
+			return err
+		}
+
+		report, err = s.store.Reports.Requeue(c.UserContext(), report.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return NewErrWithStatus(fiber.StatusNotFound, err)
+			case errors.Is(err, store.ErrReportNotFinished):
+				return NewErrWithStatus(fiber.StatusConflict, errors.New("only completed or failed reports can be requeued"))
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.auditAdminAction(c, dto.AuditAdminRequeueReport, dto.AuditTargetReport, report.ID.String())
+
+		resp := newAdminReportResponse(report)
+		if err := encode(APIResponse[AdminReportResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+type FailReportRequest struct {
+	ErrorMessage string `json:"error_message"`
+}
+
+func (r FailReportRequest) Validate() error {
+	if r.ErrorMessage == "" {
+		return errors.New("error_message is required")
+	}
+	return nil
+}
+
+// adminFailReportHandler marks a pending or running report as failed, for
+// reports stuck in generation.
+func (s *APIServer) adminFailReportHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[FailReportRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		report, err := s.adminReport(c)
+		if err != nil {
+			return err
+		}
+		if status := report.Status(); status == dto.ReportStatusCompleted || status == dto.ReportStatusFailed {
+			return NewErrWithStatus(fiber.StatusConflict, fmt.Errorf("report is already %s", status))
+		}
+
+		now := time.Now()
+		report.FailedAt = &now
+		report.ErrorMessage = &req.ErrorMessage
+		report, err = s.store.Reports.Update(c.UserContext(), report)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.auditAdminAction(c, dto.AuditAdminFailReport, dto.AuditTargetReport, report.ID.String())
+
+		resp := newAdminReportResponse(report)
+		if err := encode(APIResponse[AdminReportResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,135 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (r CreateAPIKeyRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(r.Name) > 200 {
+		return errors.New("name must be at most 200 characters")
+	}
+	if len(r.Scopes) == 0 {
+		return errors.New("scopes is required")
+	}
+	for _, scope := range r.Scopes {
+		if !slices.Contains(dto.Scopes, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Key is only returned when the key is created.
+	Key string `json:"key,omitempty"`
+}
+
+func newAPIKeyResponse(apiKey *dto.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		CreatedAt:  apiKey.CreatedAt,
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+	}
+}
+
+func (s *APIServer) createAPIKeyHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[CreateAPIKeyRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		prefix, secret, err := dto.NewAPIKey()
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		user := currentUser(c)
+		apiKey, err := s.store.APIKeys.Create(c.UserContext(), user.ID, req.Name, prefix, secret, slices.Compact(slices.Sorted(slices.Values(req.Scopes))), req.ExpiresAt)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, dto.AuditEvent{EventType: dto.AuditAPIKeyCreate, TargetType: dto.AuditTargetAPIKey, TargetID: apiKey.ID.String()})
+
+		resp := newAPIKeyResponse(apiKey)
+		resp.Key = dto.FormatAPIKey(prefix, secret)
+		if err := encode(APIResponse[APIKeyResponse]{Data: &resp}, fiber.StatusCreated, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) listAPIKeysHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user := currentUser(c)
+		apiKeys, err := s.store.APIKeys.ByUserID(c.UserContext(), user.ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := make([]APIKeyResponse, 0, len(apiKeys))
+		for i := range apiKeys {
+			resp = append(resp, newAPIKeyResponse(&apiKeys[i]))
+		}
+
+		if err := encode(APIResponse[[]APIKeyResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) deleteAPIKeyHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		apiKeyID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid api key id: %w", err))
+		}
+
+		user := currentUser(c)
+		result, err := s.store.APIKeys.Delete(c.UserContext(), user.ID, apiKeyID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return NewErrWithStatus(fiber.StatusNotFound, fmt.Errorf("api key %s not found for user %s", apiKeyID, user.ID))
+		}
+		s.audit(c, dto.AuditEvent{EventType: dto.AuditAPIKeyDelete, TargetType: dto.AuditTargetAPIKey, TargetID: apiKeyID.String()})
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully deleted api key"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
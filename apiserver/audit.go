@@ -0,0 +1,209 @@
+package apiserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/store"
+)
+
+// audit records event in the security audit log together with the client
+// and request ID of c. Events without an actor are attributed to the current
+// user, if any. Failing to record an event is logged and does not fail the
+// request.
+func (s *APIServer) audit(c *fiber.Ctx, event dto.AuditEvent) {
+	if event.ActorID == nil {
+		if user := currentUser(c); user != nil {
+			event.ActorID = &user.ID
+		}
+	}
+	if event.Outcome == "" {
+		event.Outcome = dto.AuditOutcomeSuccess
+	}
+	event.IP = c.IP()
+	event.UserAgent = c.Get(fiber.HeaderUserAgent)
+	event.RequestID, _ = c.Locals("requestid").(string)
+
+	if _, err := s.store.AuditEvents.Record(c.UserContext(), &event); err != nil {
+		slog.ErrorContext(c.UserContext(), "failed to record audit event", "error", err, "event_type", event.EventType)
+	}
+}
+
+// userEvent returns an event of eventType performed by userID on their own
+// account.
+func userEvent(eventType string, userID uuid.UUID) dto.AuditEvent {
+	return dto.AuditEvent{
+		EventType:  eventType,
+		ActorID:    &userID,
+		TargetType: dto.AuditTargetUser,
+		TargetID:   userID.String(),
+	}
+}
+
+// failedUserEvent is userEvent for a failed attempt, for reason.
+func failedUserEvent(eventType string, userID uuid.UUID, reason string) dto.AuditEvent {
+	event := userEvent(eventType, userID)
+	event.Outcome = dto.AuditOutcomeFailure
+	event.Reason = reason
+	return event
+}
+
+// sessionEvent returns an event of eventType performed by userID on one of
+// their sessions.
+func sessionEvent(eventType string, userID, sessionID uuid.UUID) dto.AuditEvent {
+	return dto.AuditEvent{
+		EventType:  eventType,
+		ActorID:    &userID,
+		TargetType: dto.AuditTargetSession,
+		TargetID:   sessionID.String(),
+	}
+}
+
+// memberEvent returns an event of eventType on the membership of userID in
+// orgID, naming role when the member was given one.
+func memberEvent(eventType string, orgID, userID uuid.UUID, role string) dto.AuditEvent {
+	return dto.AuditEvent{
+		EventType:  eventType,
+		Reason:     role,
+		TargetType: dto.AuditTargetMembership,
+		TargetID:   orgID.String() + "/" + userID.String(),
+	}
+}
+
+type AuditEventResponse struct {
+	ID         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	EventType  string     `json:"event_type"`
+	Outcome    string     `json:"outcome"`
+	Reason     string     `json:"reason,omitempty"`
+	ActorID    *uuid.UUID `json:"actor_id"`
+	TargetType string     `json:"target_type,omitempty"`
+	TargetID   string     `json:"target_id,omitempty"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	RequestID  string     `json:"request_id"`
+}
+
+func newAuditEventResponse(event *dto.AuditEvent) AuditEventResponse {
+	return AuditEventResponse{
+		ID:         event.ID,
+		CreatedAt:  event.CreatedAt,
+		EventType:  event.EventType,
+		Outcome:    event.Outcome,
+		Reason:     event.Reason,
+		ActorID:    event.ActorID,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		IP:         event.IP,
+		UserAgent:  event.UserAgent,
+		RequestID:  event.RequestID,
+	}
+}
+
+// optionalTimeQuery parses the RFC 3339 query parameter key, if present.
+func optionalTimeQuery(c *fiber.Ctx, key string) (*time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return &t, nil
+}
+
+// auditEventFilter reads the filter of the audit event endpoints from the
+// query parameters event_type, outcome, actor_id, target_type, target_id,
+// since and until.
+func auditEventFilter(c *fiber.Ctx) (store.AuditEventFilter, error) {
+	filter := store.AuditEventFilter{
+		EventType:  c.Query("event_type"),
+		Outcome:    c.Query("outcome"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+	if filter.Outcome != "" && !slices.Contains([]string{dto.AuditOutcomeSuccess, dto.AuditOutcomeFailure}, filter.Outcome) {
+		return filter, fmt.Errorf("unknown outcome %q", filter.Outcome)
+	}
+
+	var err error
+	if filter.ActorID, err = optionalUUIDQuery(c, "actor_id"); err != nil {
+		return filter, err
+	}
+	if filter.Since, err = optionalTimeQuery(c, "since"); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = optionalTimeQuery(c, "until"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func (s *APIServer) adminListAuditEventsHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		filter, err := auditEventFilter(c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+		if filter.Limit, filter.Offset, err = pagination(c); err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		events, err := s.store.AuditEvents.Search(c.UserContext(), filter)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := make([]AuditEventResponse, 0, len(events))
+		for i := range events {
+			resp = append(resp, newAuditEventResponse(&events[i]))
+		}
+
+		if err := encode(APIResponse[[]AuditEventResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// adminExportAuditEventsHandler streams every event matching the filter as
+// newline-delimited JSON, oldest first. The response is written after the
+// handler returns, so errors past that point can only be logged and end the
+// stream early.
+func (s *APIServer) adminExportAuditEventsHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		filter, err := auditEventFilter(c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		// c must not be used once the handler returns.
+		ctx := context.WithoutCancel(c.UserContext())
+
+		c.Set(fiber.HeaderContentType, "application/x-ndjson")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="audit-events.ndjson"`)
+		c.Status(fiber.StatusOK)
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			encoder := json.NewEncoder(w)
+			err := s.store.AuditEvents.Export(ctx, filter, func(event *dto.AuditEvent) error {
+				return encoder.Encode(newAuditEventResponse(event))
+			})
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to export audit events", "error", err)
+			}
+			if err := w.Flush(); err != nil {
+				slog.ErrorContext(ctx, "failed to flush audit event export", "error", err)
+			}
+		})
+		return nil
+	})
+}
@@ -0,0 +1,129 @@
+package apiserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/store"
+)
+
+// AccessTokenDenylist rejects revoked access tokens before they expire. It
+// caches revoked tokens until their expiry, and tokens found not revoked for
+// cacheTTL, so that most requests do not hit the database. A token revoked by
+// another replica is therefore rejected after at most cacheTTL.
+type AccessTokenDenylist struct {
+	store    *store.RevokedAccessTokenStore
+	cacheTTL time.Duration
+
+	mu      sync.Mutex
+	revoked map[uuid.UUID]time.Time
+	allowed map[uuid.UUID]time.Time
+}
+
+func NewAccessTokenDenylist(store *store.RevokedAccessTokenStore, cacheTTL time.Duration) *AccessTokenDenylist {
+	return &AccessTokenDenylist{
+		store:    store,
+		cacheTTL: cacheTTL,
+		revoked:  make(map[uuid.UUID]time.Time),
+		allowed:  make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (d *AccessTokenDenylist) IsRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	now := time.Now()
+
+	d.mu.Lock()
+	if expiresAt, ok := d.revoked[jti]; ok && expiresAt.After(now) {
+		d.mu.Unlock()
+		return true, nil
+	}
+	if until, ok := d.allowed[jti]; ok && until.After(now) {
+		d.mu.Unlock()
+		return false, nil
+	}
+	d.mu.Unlock()
+
+	revoked, err := d.store.ByJTI(ctx, jti)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("failed to check access token denylist: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if revoked != nil {
+		d.revoked[jti] = revoked.ExpiresAt
+		return true, nil
+	}
+	if d.cacheTTL > 0 {
+		d.allowed[jti] = now.Add(d.cacheTTL)
+	}
+	return false, nil
+}
+
+// RevokeSession revokes the latest access token of a session.
+func (d *AccessTokenDenylist) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	revoked, err := d.store.RevokeSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	d.remember(revoked)
+	return nil
+}
+
+// RevokeUserSessions revokes the latest access token of every session of a
+// user.
+func (d *AccessTokenDenylist) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	revoked, err := d.store.RevokeUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	d.remember(revoked)
+	return nil
+}
+
+// RevokeOtherSessions revokes the latest access token of every session of a
+// user but keepSessionID.
+func (d *AccessTokenDenylist) RevokeOtherSessions(ctx context.Context, userID, keepSessionID uuid.UUID) error {
+	revoked, err := d.store.RevokeOtherSessions(ctx, userID, keepSessionID)
+	if err != nil {
+		return err
+	}
+	d.remember(revoked)
+	return nil
+}
+
+func (d *AccessTokenDenylist) remember(revoked []dto.RevokedAccessToken) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, token := range revoked {
+		d.revoked[token.JTI] = token.ExpiresAt
+		delete(d.allowed, token.JTI)
+	}
+}
+
+// Cleanup forgets expired tokens, in the cache and in the database.
+func (d *AccessTokenDenylist) Cleanup(ctx context.Context) error {
+	now := time.Now()
+
+	d.mu.Lock()
+	for jti, expiresAt := range d.revoked {
+		if !expiresAt.After(now) {
+			delete(d.revoked, jti)
+		}
+	}
+	for jti, until := range d.allowed {
+		if !until.After(now) {
+			delete(d.allowed, jti)
+		}
+	}
+	d.mu.Unlock()
+
+	_, err := d.store.DeleteExpired(ctx, now)
+	return err
+}
@@ -0,0 +1,47 @@
+package apiserver
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// maxEmailLength is the length of users.email.
+const maxEmailLength = 320
+
+var errInvalidEmail = errors.New("email is invalid")
+
+// NormalizeEmail trims email and returns it with its domain in lower case
+// ASCII, converting internationalized domains to punycode, so that every
+// spelling of an address maps to the same account. The local part is kept as
+// given; users are looked up by email case-insensitively.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return "", errInvalidEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	domain, err := normalizeDomain(email[at+1:])
+	if err != nil {
+		return "", errInvalidEmail
+	}
+	return email[:at+1] + domain, nil
+}
+
+func normalizeDomain(domain string) (string, error) {
+	return idna.Lookup.ToASCII(strings.TrimSpace(domain))
+}
+
+// lookupEmail normalizes email to look up an existing account. Emails that do
+// not normalize, such as those of accounts created before emails were
+// validated, are only trimmed.
+func lookupEmail(email string) string {
+	if normalized, err := NormalizeEmail(email); err == nil {
+		return normalized
+	}
+	return strings.TrimSpace(email)
+}
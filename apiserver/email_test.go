@@ -0,0 +1,33 @@
+package apiserver_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/apiserver"
+)
+
+var _ = Describe("NormalizeEmail", func() {
+	DescribeTable("should normalize the domain",
+		func(email, normalized string) {
+			Expect(apiserver.NormalizeEmail(email)).To(Equal(normalized))
+		},
+		Entry("already normalized", "bob@example.com", "bob@example.com"),
+		Entry("surrounding spaces", "  bob@example.com\n", "bob@example.com"),
+		Entry("upper case domain", "bob@Example.COM", "bob@example.com"),
+		Entry("upper case local part", "Bob@example.com", "Bob@example.com"),
+		Entry("internationalized domain", "bob@Bücher.example", "bob@xn--bcher-kva.example"),
+		Entry("punycode domain", "bob@xn--bcher-kva.example", "bob@xn--bcher-kva.example"),
+	)
+
+	DescribeTable("should reject invalid emails",
+		func(email string) {
+			_, err := apiserver.NormalizeEmail(email)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("no domain", "bob"),
+		Entry("display name", "Bob <bob@example.com>"),
+		Entry("two addresses", "bob@example.com, alice@example.com"),
+		Entry("invalid domain", "bob@exa mple.com"),
+	)
+})
@@ -1,12 +1,20 @@
 package apiserver
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/logging"
+	"github.com/talvor/asyncapi/mailer"
+	"github.com/talvor/asyncapi/store"
 )
 
 type APIResponse[T any] struct {
@@ -17,12 +25,17 @@ type APIResponse[T any] struct {
 type SignupRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// InviteCode is required by the invite signup policy.
+	InviteCode string `json:"invite_code"`
 }
 
 func (r SignupRequest) Validate() error {
 	if r.Email == "" {
 		return errors.New("email is required")
 	}
+	if len(r.Email) > maxEmailLength {
+		return errors.New("email must be at most 320 characters")
+	}
 	if r.Password == "" {
 		return errors.New("password is required")
 	}
@@ -35,15 +48,32 @@ func (s *APIServer) ping() fiber.Handler {
 	})
 }
 
+func (s *APIServer) jwksHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		if err := encode(s.jwtManager.JWKS(), fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
 func (s *APIServer) signupHandler() fiber.Handler {
 	return handler(func(c *fiber.Ctx) error {
 		req, err := decode[SignupRequest](c)
 		if err != nil {
 			return NewErrWithStatus(fiber.StatusBadRequest, err)
 		}
+		if req.Email, err = NormalizeEmail(req.Email); err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		if !s.signupPolicy.AllowsEmail(req.Email) {
+			return NewErrWithStatus(fiber.StatusBadRequest, errors.New("signup is restricted to allowed email domains"))
+		}
 
 		// Check if user already exists
-		existingUser, err := s.store.Users.ByEmail(c.Context(), req.Email)
+		existingUser, err := s.store.Users.ByEmail(c.UserContext(), req.Email)
 		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			return NewErrWithStatus(fiber.StatusInternalServerError, err)
 		}
@@ -51,10 +81,36 @@ func (s *APIServer) signupHandler() fiber.Handler {
 			return NewErrWithStatus(fiber.StatusConflict, fmt.Errorf("email already registered"))
 		}
 
-		_, err = s.store.Users.CreateUser(c.Context(), req.Email, req.Password)
+		var inviteCode *dto.InviteCode
+		if s.signupPolicy.RequiresInvite() {
+			if req.InviteCode == "" {
+				return NewErrWithStatus(fiber.StatusBadRequest, errors.New("invite_code is required"))
+			}
+			inviteCode, err = s.store.InviteCodes.Use(c.UserContext(), req.InviteCode)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return NewErrWithStatus(fiber.StatusBadRequest, errors.New("invalid or expired invite code"))
+				}
+				return NewErrWithStatus(fiber.StatusInternalServerError, err)
+			}
+		}
+
+		user, err := s.store.Users.CreateUser(c.UserContext(), req.Email, req.Password)
 		if err != nil {
+			if inviteCode != nil {
+				if _, err := s.store.InviteCodes.Release(c.UserContext(), inviteCode.ID); err != nil {
+					slog.ErrorContext(c.UserContext(), "failed to release invite code", "error", err, "invite_code_id", inviteCode.ID)
+				}
+			}
 			return NewErrWithStatus(fiber.StatusInternalServerError, err)
 		}
+		s.audit(c, userEvent(dto.AuditSignup, user.ID))
+
+		// The user can ask for another email, failing the signup would only
+		// leave them unable to sign up again with the same address.
+		if err := s.sendEmailVerification(c.UserContext(), user); err != nil {
+			slog.ErrorContext(c.UserContext(), "failed to send email verification", "error", err, logging.KeyUserID, user.ID)
+		}
 
 		if err = encode(APIResponse[struct{}]{Message: "successfully signed up user"}, fiber.StatusCreated, c); err != nil {
 			return NewErrWithStatus(fiber.StatusInternalServerError, err)
@@ -64,20 +120,29 @@ func (s *APIServer) signupHandler() fiber.Handler {
 	})
 }
 
+var errInvalidCredentials = errors.New("invalid email or password")
+
 type SigninRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
+// SigninResponse holds either a token pair or, for users with two-factor
+// authentication, a challenge token to complete the signin with.
 type SigninResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken    string `json:"access_token,omitempty"`
+	RefreshToken   string `json:"refresh_token,omitempty"`
+	MFARequired    bool   `json:"mfa_required,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
 }
 
 func (r SigninRequest) Validate() error {
 	if r.Email == "" {
 		return errors.New("email is required")
 	}
+	if len(r.Email) > maxEmailLength {
+		return errors.New("email must be at most 320 characters")
+	}
 	if r.Password == "" {
 		return errors.New("password is required")
 	}
@@ -90,25 +155,87 @@ func (s *APIServer) signinHandler() fiber.Handler {
 		if err != nil {
 			return NewErrWithStatus(fiber.StatusBadRequest, err)
 		}
+		req.Email = lookupEmail(req.Email)
 
-		user, err := s.store.Users.ByEmail(c.Context(), req.Email)
-		if err != nil && !errors.Is(err, sql.ErrNoRows) {
-			return NewErrWithStatus(fiber.StatusNotFound, err)
+		// Unknown emails, locked or disabled accounts and wrong passwords all
+		// get the same response, after a password comparison, so that the
+		// endpoint does not reveal which accounts exist.
+		user, err := s.store.Users.ByEmail(c.UserContext(), req.Email)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusInternalServerError, err)
+			}
+			_ = dto.CheckPasswordHash(req.Password, dummyPasswordHash())
+			// The attempted email is the only thing tying attempts on
+			// unknown accounts together.
+			s.audit(c, dto.AuditEvent{
+				EventType:  dto.AuditSignin,
+				Outcome:    dto.AuditOutcomeFailure,
+				Reason:     "unknown_email",
+				TargetType: dto.AuditTargetEmail,
+				TargetID:   strings.ToLower(req.Email),
+			})
+			return NewErrWithStatus(fiber.StatusUnauthorized, errInvalidCredentials)
+		}
+
+		if user.IsDisabled() {
+			_ = user.ComparePassword(req.Password)
+			s.audit(c, failedUserEvent(dto.AuditSignin, user.ID, "disabled"))
+			return NewErrWithStatus(fiber.StatusUnauthorized, fmt.Errorf("user %s is disabled: %w", user.ID, errInvalidCredentials))
+		}
+
+		// The attempt is counted as failed before the password is compared,
+		// so that parallel guesses cannot all get in before the lockout.
+		if _, err := s.store.Users.ClaimSigninAttempt(c.UserContext(), user.ID, s.signinThrottle, time.Now()); err != nil {
+			if errors.Is(err, store.ErrUserLocked) {
+				_ = user.ComparePassword(req.Password)
+				s.audit(c, failedUserEvent(dto.AuditSignin, user.ID, "locked"))
+				return NewErrWithStatus(fiber.StatusUnauthorized, fmt.Errorf("%w: %w", err, errInvalidCredentials))
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
 		}
 
 		if err = user.ComparePassword(req.Password); err != nil {
-			return NewErrWithStatus(fiber.StatusUnauthorized, err)
+			s.audit(c, failedUserEvent(dto.AuditSignin, user.ID, "invalid_password"))
+			return NewErrWithStatus(fiber.StatusUnauthorized, fmt.Errorf("%w: %w", errInvalidCredentials, err))
 		}
 
-		tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID)
-		if err != nil {
+		if _, err := s.store.Users.ResetFailedSignins(c.UserContext(), user.ID); err != nil {
 			return NewErrWithStatus(fiber.StatusInternalServerError, err)
 		}
 
-		_, err = s.store.RefreshTokens.DeleteUserTokensThenCreate(c.Context(), user.ID, tokenPair.RefreshToken)
+		// The plain password is only at hand now, a failed rehash is retried
+		// on the next signin.
+		if dto.PasswordNeedsRehash(user.HashedPassword) {
+			if _, err := s.store.Users.UpdatePassword(c.UserContext(), user.ID, req.Password); err != nil {
+				slog.ErrorContext(c.UserContext(), "failed to rehash password", "error", err, logging.KeyUserID, user.ID)
+			}
+		}
+
+		if user.IsTOTPEnabled() {
+			challengeToken, err := s.jwtManager.GenerateChallengeToken(user.ID)
+			if err != nil {
+				return NewErrWithStatus(fiber.StatusInternalServerError, err)
+			}
+			event := userEvent(dto.AuditSignin, user.ID)
+			event.Reason = "mfa_required"
+			s.audit(c, event)
+			if err := encode(APIResponse[SigninResponse]{
+				Data: &SigninResponse{
+					MFARequired:    true,
+					ChallengeToken: challengeToken.Raw,
+				},
+			}, fiber.StatusOK, c); err != nil {
+				return NewErrWithStatus(fiber.StatusInternalServerError, err)
+			}
+			return nil
+		}
+
+		tokenPair, err := s.startSession(c, user, []string{AMRPassword})
 		if err != nil {
 			return NewErrWithStatus(fiber.StatusInternalServerError, err)
 		}
+		s.audit(c, userEvent(dto.AuditSignin, user.ID))
 
 		if err := encode(APIResponse[SigninResponse]{
 			Data: &SigninResponse{
@@ -157,7 +284,7 @@ func (s *APIServer) refreshTokenHandler() fiber.Handler {
 			return NewErrWithStatus(fiber.StatusUnauthorized, err)
 		}
 
-		currentRefreshTokenRecord, err := s.store.RefreshTokens.ByPrimaryKey(c.Context(), userID, currentRefreshToken)
+		currentRefreshTokenRecord, err := s.store.RefreshTokens.ByPrimaryKey(c.UserContext(), userID, currentRefreshToken)
 		if err != nil {
 			status := fiber.StatusInternalServerError
 			if errors.Is(err, sql.ErrNoRows) {
@@ -166,20 +293,56 @@ func (s *APIServer) refreshTokenHandler() fiber.Handler {
 			return NewErrWithStatus(status, err)
 		}
 
+		sessionID := currentRefreshTokenRecord.SessionID
+		if currentRefreshTokenRecord.RotatedAt != nil {
+			return s.revokeReusedRefreshToken(c, userID, sessionID)
+		}
+
 		if currentRefreshTokenRecord.ExpiresAt.Before(time.Now()) {
 			return NewErrWithStatus(fiber.StatusUnauthorized, errors.New("refresh token expired"))
 		}
 
-		tokenPair, err := s.jwtManager.GenerateTokenPair(userID)
+		session, err := s.store.Sessions.ByPrimaryKey(c.UserContext(), userID, sessionID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		sessionExpiresAt := s.sessionExpiresAt(session)
+		if !sessionExpiresAt.IsZero() && sessionExpiresAt.Before(time.Now()) {
+			return NewErrWithStatus(fiber.StatusUnauthorized, errors.New("session expired"))
+		}
+
+		// Permissions are reloaded so that role changes apply on the next
+		// refresh.
+		permissions, err := s.store.Roles.PermissionsByUserID(c.UserContext(), userID)
 		if err != nil {
 			return NewErrWithStatus(fiber.StatusInternalServerError, err)
 		}
 
-		_, err = s.store.RefreshTokens.DeleteUserTokensThenCreate(c.Context(), userID, tokenPair.RefreshToken)
+		amr := s.jwtManager.GetAMRFromToken(currentRefreshToken)
+		tokenPair, err := s.jwtManager.GenerateTokenPair(userID, sessionID, sessionExpiresAt, permissions, amr)
 		if err != nil {
 			return NewErrWithStatus(fiber.StatusInternalServerError, err)
 		}
 
+		_, err = s.store.RefreshTokens.Rotate(c.UserContext(), userID, sessionID, currentRefreshToken, tokenPair.RefreshToken)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// The token was rotated by a concurrent request.
+				return s.revokeReusedRefreshToken(c, userID, sessionID)
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if _, err := s.store.Sessions.Touch(c.UserContext(), sessionID, c.Get(fiber.HeaderUserAgent), c.IP()); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if err := s.recordAccessToken(c.UserContext(), userID, sessionID, tokenPair); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, sessionEvent(dto.AuditRefresh, userID, sessionID))
+
 		if err := encode(APIResponse[RefreshTokenResponse]{
 			Data: &RefreshTokenResponse{
 				AccessToken:  tokenPair.AccessToken.Raw,
@@ -192,3 +355,350 @@ func (s *APIServer) refreshTokenHandler() fiber.Handler {
 		return nil
 	})
 }
+
+// revokeReusedRefreshToken handles a refresh token presented after it was
+// rotated. Either the client or an attacker holds a stolen copy, and there is
+// no telling which, so the whole session (the token family) is revoked.
+func (s *APIServer) revokeReusedRefreshToken(c *fiber.Ctx, userID, sessionID uuid.UUID) error {
+	slog.WarnContext(c.UserContext(), "refresh token reuse detected, revoking session",
+		"security_event", "refresh_token_reuse",
+		logging.KeyUserID, userID,
+		"session_id", sessionID,
+		"ip", c.IP(),
+	)
+
+	if _, err := s.revokeSession(c.UserContext(), userID, sessionID); err != nil {
+		return NewErrWithStatus(fiber.StatusInternalServerError, err)
+	}
+	event := sessionEvent(dto.AuditRefreshTokenReuse, userID, sessionID)
+	event.Outcome = dto.AuditOutcomeFailure
+	s.audit(c, event)
+
+	return NewErrWithStatus(fiber.StatusUnauthorized, errors.New("refresh token reused"))
+}
+
+type SignoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (r SignoutRequest) Validate() error {
+	if r.RefreshToken == "" {
+		return errors.New("refresh_token is required")
+	}
+	return nil
+}
+
+func (s *APIServer) signoutHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[SignoutRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		refreshToken, err := s.jwtManager.Parse(req.RefreshToken)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusUnauthorized, err)
+		}
+
+		user := currentUser(c)
+		refreshTokenRecord, err := s.store.RefreshTokens.ByPrimaryKey(c.UserContext(), user.ID, refreshToken)
+		if err != nil {
+			status := fiber.StatusInternalServerError
+			if errors.Is(err, sql.ErrNoRows) {
+				status = fiber.StatusUnauthorized
+			}
+			return NewErrWithStatus(status, err)
+		}
+
+		if _, err := s.revokeSession(c.UserContext(), user.ID, refreshTokenRecord.SessionID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, sessionEvent(dto.AuditSignout, user.ID, refreshTokenRecord.SessionID))
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully signed out"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		return nil
+	})
+}
+
+func (s *APIServer) signoutAllHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user := currentUser(c)
+		if err := s.revokeUserSessions(c.UserContext(), user.ID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, userEvent(dto.AuditSignoutAll, user.ID))
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully signed out of all sessions"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		return nil
+	})
+}
+
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+}
+
+func (s *APIServer) listSessionsHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user := currentUser(c)
+		sessions, err := s.store.Sessions.ByUserID(c.UserContext(), user.ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		currentSessionID, _ := c.Locals("session_id").(uuid.UUID)
+		resp := make([]SessionResponse, 0, len(sessions))
+		for _, session := range sessions {
+			resp = append(resp, SessionResponse{
+				ID:         session.ID,
+				UserAgent:  session.UserAgent,
+				IPAddress:  session.IPAddress,
+				CreatedAt:  session.CreatedAt,
+				LastUsedAt: session.LastUsedAt,
+				Current:    session.ID == currentSessionID,
+			})
+		}
+
+		if err := encode(APIResponse[[]SessionResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		return nil
+	})
+}
+
+func (s *APIServer) deleteSessionHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		sessionID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid session id: %w", err))
+		}
+
+		user := currentUser(c)
+		result, err := s.revokeSession(c.UserContext(), user.ID, sessionID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return NewErrWithStatus(fiber.StatusNotFound, fmt.Errorf("session %s not found for user %s", sessionID, user.ID))
+		}
+		s.audit(c, sessionEvent(dto.AuditSessionRevoke, user.ID, sessionID))
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully revoked session"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		return nil
+	})
+}
+
+// sendEmailVerification emails user a new verification token, invalidating
+// any sent before.
+func (s *APIServer) sendEmailVerification(ctx context.Context, user *dto.User) error {
+	if _, err := s.store.UserTokens.DeleteUserTokens(ctx, user.ID, dto.UserTokenEmailVerification); err != nil {
+		return err
+	}
+
+	token, err := dto.NewSecretToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.config.EmailVerificationTTL)
+	if _, err := s.store.UserTokens.Create(ctx, user.ID, dto.UserTokenEmailVerification, token, expiresAt); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Use the following token to verify your email address, it expires at %s:\n\n%s\n",
+			expiresAt.UTC().Format(time.RFC1123), token),
+	})
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (r VerifyEmailRequest) Validate() error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+func (s *APIServer) verifyEmailHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[VerifyEmailRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		userToken, err := s.store.UserTokens.Consume(c.UserContext(), dto.UserTokenEmailVerification, req.Token)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusBadRequest, errors.New("invalid or expired token"))
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if _, err := s.store.Users.MarkEmailVerified(c.UserContext(), userToken.UserID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully verified email"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		return nil
+	})
+}
+
+func (s *APIServer) resendEmailVerificationHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user := currentUser(c)
+		if user.IsEmailVerified() {
+			return NewErrWithStatus(fiber.StatusConflict, errors.New("email already verified"))
+		}
+
+		if err := s.sendEmailVerification(c.UserContext(), user); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if err := encode(APIResponse[struct{}]{Message: "verification email sent"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		return nil
+	})
+}
+
+// sendPasswordReset emails user a new password reset token, invalidating any
+// sent before.
+func (s *APIServer) sendPasswordReset(ctx context.Context, user *dto.User) error {
+	if _, err := s.store.UserTokens.DeleteUserTokens(ctx, user.ID, dto.UserTokenPasswordReset); err != nil {
+		return err
+	}
+
+	token, err := dto.NewSecretToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.config.PasswordResetTTL)
+	if _, err := s.store.UserTokens.Create(ctx, user.ID, dto.UserTokenPasswordReset, token, expiresAt); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use the following token to choose a new password, it expires at %s:\n\n%s\n\n"+
+			"If you did not ask to reset your password, you can ignore this email.\n",
+			expiresAt.UTC().Format(time.RFC1123), token),
+	})
+}
+
+type RequestPasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+func (r RequestPasswordResetRequest) Validate() error {
+	if r.Email == "" {
+		return errors.New("email is required")
+	}
+	return nil
+}
+
+func (s *APIServer) requestPasswordResetHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[RequestPasswordResetRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+		req.Email = lookupEmail(req.Email)
+
+		// The email is sent in the background and the response is the same
+		// whether or not the user exists, so that neither its content nor its
+		// timing reveals registered addresses.
+		ctx := context.WithoutCancel(c.UserContext())
+		go func() {
+			user, err := s.store.Users.ByEmail(ctx, req.Email)
+			if err != nil {
+				if !errors.Is(err, sql.ErrNoRows) {
+					slog.ErrorContext(ctx, "failed to get user for password reset", "error", err)
+				}
+				return
+			}
+			if err := s.sendPasswordReset(ctx, user); err != nil {
+				slog.ErrorContext(ctx, "failed to send password reset", "error", err, logging.KeyUserID, user.ID)
+			}
+		}()
+
+		if err := encode(APIResponse[struct{}]{Message: "if the email is registered, a password reset token has been sent"}, fiber.StatusAccepted, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		return nil
+	})
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (r ConfirmPasswordResetRequest) Validate() error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	if r.Password == "" {
+		return errors.New("password is required")
+	}
+	return nil
+}
+
+func (s *APIServer) confirmPasswordResetHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[ConfirmPasswordResetRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		userToken, err := s.store.UserTokens.Consume(c.UserContext(), dto.UserTokenPasswordReset, req.Token)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusBadRequest, errors.New("invalid or expired token"))
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if _, err := s.store.Users.UpdatePassword(c.UserContext(), userToken.UserID, req.Password); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		// Whoever knew the old password must not stay signed in.
+		if err := s.revokeUserSessions(c.UserContext(), userToken.UserID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if _, err := s.store.UserTokens.DeleteUserTokens(c.UserContext(), userToken.UserID, dto.UserTokenPasswordReset); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, userEvent(dto.AuditPasswordReset, userToken.UserID))
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully reset password"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		return nil
+	})
+}
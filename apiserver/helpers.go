@@ -6,6 +6,8 @@ import (
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/logging"
 )
 
 type ErrWithStatus struct {
@@ -26,6 +28,8 @@ func NewErrWithStatus(status int, err error) error {
 
 func handler(f func(*fiber.Ctx) error) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		c.SetUserContext(logging.With(c.UserContext(), logging.KeyRoute, c.Route().Path))
+
 		if err := f(c); err != nil {
 			status := fiber.StatusInternalServerError
 			msg := http.StatusText(status)
@@ -38,12 +42,12 @@ func handler(f func(*fiber.Ctx) error) fiber.Handler {
 				}
 			}
 
-			slog.Error("error executing handler", "error", err, "status", status, "message", msg)
+			slog.ErrorContext(c.UserContext(), "error executing handler", "error", err, "status", status, "message", msg)
 
 			if err := c.Status(status).JSON(APIResponse[struct{}]{
 				Message: msg,
 			}); err != nil {
-				slog.Error("error sending response", "error", err)
+				slog.ErrorContext(c.UserContext(), "error sending response", "error", err)
 			}
 		}
 		return nil
@@ -75,3 +79,9 @@ func decode[T Validator](c *fiber.Ctx) (T, error) {
 
 	return t, nil
 }
+
+// currentUser returns the user authenticated by AuthMiddleware.
+func currentUser(c *fiber.Ctx) *dto.User {
+	user, _ := c.Locals("user").(*dto.User)
+	return user
+}
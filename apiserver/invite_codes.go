@@ -0,0 +1,117 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type CreateInviteCodeRequest struct {
+	MaxUses   int        `json:"max_uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (r CreateInviteCodeRequest) Validate() error {
+	if r.MaxUses < 1 {
+		return errors.New("max_uses must be at least 1")
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+type InviteCodeResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// Code is only returned when the invite code is created.
+	Code string `json:"code,omitempty"`
+}
+
+func newInviteCodeResponse(inviteCode *dto.InviteCode) InviteCodeResponse {
+	return InviteCodeResponse{
+		ID:        inviteCode.ID,
+		MaxUses:   inviteCode.MaxUses,
+		Uses:      inviteCode.Uses,
+		CreatedBy: inviteCode.CreatedBy,
+		CreatedAt: inviteCode.CreatedAt,
+		ExpiresAt: inviteCode.ExpiresAt,
+	}
+}
+
+func (s *APIServer) adminCreateInviteCodeHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[CreateInviteCodeRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		code, err := dto.NewSecretToken()
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		inviteCode, err := s.store.InviteCodes.Create(c.UserContext(), code, req.MaxUses, currentUser(c).ID, req.ExpiresAt)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.auditAdminAction(c, dto.AuditAdminCreateInvite, dto.AuditTargetInvite, inviteCode.ID.String())
+
+		resp := newInviteCodeResponse(inviteCode)
+		resp.Code = code
+		if err := encode(APIResponse[InviteCodeResponse]{Data: &resp}, fiber.StatusCreated, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) adminListInviteCodesHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		inviteCodes, err := s.store.InviteCodes.List(c.UserContext())
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := make([]InviteCodeResponse, 0, len(inviteCodes))
+		for i := range inviteCodes {
+			resp = append(resp, newInviteCodeResponse(&inviteCodes[i]))
+		}
+
+		if err := encode(APIResponse[[]InviteCodeResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) adminDeleteInviteCodeHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		inviteCodeID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid invite code id: %w", err))
+		}
+
+		result, err := s.store.InviteCodes.Delete(c.UserContext(), inviteCodeID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return NewErrWithStatus(fiber.StatusNotFound, fmt.Errorf("invite code %s not found", inviteCodeID))
+		}
+		s.auditAdminAction(c, dto.AuditAdminRevokeInvite, dto.AuditTargetInvite, inviteCodeID.String())
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully revoked invite code"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
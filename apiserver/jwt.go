@@ -2,6 +2,8 @@ package apiserver
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,16 +11,56 @@ import (
 	"github.com/talvor/asyncapi/config"
 )
 
-var signingMethod = jwt.SigningMethodHS256
-
 type JwtManager struct {
-	config *config.Config
+	config           *config.Config
+	issuer           string
+	signingKey       signingKey
+	verificationKeys map[string]verificationKey
 }
 
-func NewJwtManager(config *config.Config) *JwtManager {
-	return &JwtManager{
-		config: config,
+// NewJwtManager signs tokens with the private key in JwtSigningKeyFile and
+// verifies them with its public key or any of JwtVerificationKeyFiles,
+// selected by the kid header. Keys are identified by their file name. Without
+// a signing key file, tokens are signed with HS256 and JwtSecret. The issuer
+// defaults to the API server's address.
+func NewJwtManager(config *config.Config) (*JwtManager, error) {
+	j := &JwtManager{
+		config:           config,
+		issuer:           config.JwtIssuer,
+		verificationKeys: make(map[string]verificationKey),
+	}
+	if j.issuer == "" {
+		j.issuer = "http://" + config.APIHost + ":" + config.APIPort
+	}
+
+	if config.JwtSigningKeyFile == "" {
+		j.signingKey = signingKey{
+			verificationKey: verificationKey{method: jwt.SigningMethodHS256, key: []byte(config.JwtSecret)},
+			private:         []byte(config.JwtSecret),
+		}
+		j.verificationKeys[""] = j.signingKey.verificationKey
+		return j, nil
+	}
+
+	key, err := loadSigningKey(config.JwtSigningKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwt signing key: %w", err)
+	}
+	j.signingKey = key
+	j.verificationKeys[key.id] = key.verificationKey
+
+	for _, path := range config.JwtVerificationKeyFiles {
+		key, err := loadVerificationKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwt verification key: %w", err)
+		}
+		if _, ok := j.verificationKeys[key.id]; ok {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.id)
+		}
+		j.verificationKeys[key.id] = key
 	}
+
+	return j, nil
 }
 
 type TokenPair struct {
@@ -26,19 +68,48 @@ type TokenPair struct {
 	RefreshToken *jwt.Token
 }
 
+// Authentication methods of the amr claim, as registered in RFC 8176.
+const (
+	AMRPassword    = "pwd"
+	AMRMultiFactor = "mfa"
+)
+
 type CustomClaims struct {
 	TokenType string `json:"token_type"`
+	SessionID string `json:"sid,omitempty"`
+	// Permissions granted to the user through their roles, on access tokens
+	// only. They are reloaded on every refresh.
+	Permissions []string `json:"perms,omitempty"`
+	// Methods the session was signed in with. Refreshing carries them over
+	// from the refresh token.
+	AMR []string `json:"amr,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Parse verifies the signature of token and validates its exp, nbf and iss
+// claims, as well as aud when JwtAudience is set, allowing JwtLeeway of clock
+// skew.
 func (j *JwtManager) Parse(token string) (*jwt.Token, error) {
-	parser := jwt.NewParser()
+	options := []jwt.ParserOption{
+		jwt.WithIssuer(j.issuer),
+		jwt.WithLeeway(j.config.JwtLeeway),
+		jwt.WithExpirationRequired(),
+	}
+	if j.config.JwtAudience != "" {
+		options = append(options, jwt.WithAudience(j.config.JwtAudience))
+	}
+	parser := jwt.NewParser(options...)
 
 	jwtToken, err := parser.Parse(token, func(t *jwt.Token) (interface{}, error) {
-		if t.Method != signingMethod {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := j.verificationKeys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if t.Method.Alg() != key.method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
-		return []byte(j.config.JwtSecret), nil
+		return key.key, nil
 	})
 
 	if err != nil {
@@ -48,26 +119,47 @@ func (j *JwtManager) Parse(token string) (*jwt.Token, error) {
 }
 
 func (j *JwtManager) IsAccessToken(token *jwt.Token) bool {
+	return tokenType(token) == "access"
+}
+
+func (j *JwtManager) IsChallengeToken(token *jwt.Token) bool {
+	return tokenType(token) == "mfa_challenge"
+}
+
+func tokenType(token *jwt.Token) string {
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return false
+		return ""
 	}
-	tokenType, ok := claims["token_type"].(string)
-	if !ok {
-		return false
-	}
-	return tokenType == "access"
+	tokenType, _ := claims["token_type"].(string)
+	return tokenType
 }
 
-func (j *JwtManager) GenerateTokenPair(userID uuid.UUID) (*TokenPair, error) {
+// GenerateTokenPair issues an access and a refresh token for a session, the
+// access token carrying permissions and both carrying the authentication
+// methods amr. When sessionExpiresAt is not zero, neither token outlives it.
+func (j *JwtManager) GenerateTokenPair(userID, sessionID uuid.UUID, sessionExpiresAt time.Time, permissions, amr []string) (*TokenPair, error) {
 	now := time.Now()
-	issuer := "http://" + j.config.APIHost + ":" + j.config.APIPort
+	expiresAt := func(ttl time.Duration) *jwt.NumericDate {
+		exp := now.Add(ttl)
+		if !sessionExpiresAt.IsZero() && sessionExpiresAt.Before(exp) {
+			exp = sessionExpiresAt
+		}
+		return jwt.NewNumericDate(exp)
+	}
+
 	claims := CustomClaims{
-		TokenType: "access",
+		TokenType:   "access",
+		SessionID:   sessionID.String(),
+		Permissions: permissions,
+		AMR:         amr,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Subject:   userID.String(),
-			Issuer:    issuer,
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute * 15)),
+			Issuer:    j.issuer,
+			Audience:  j.audience(),
+			ExpiresAt: expiresAt(j.config.JwtAccessTokenTTL),
+			NotBefore: jwt.NewNumericDate(now),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
@@ -78,10 +170,15 @@ func (j *JwtManager) GenerateTokenPair(userID uuid.UUID) (*TokenPair, error) {
 		return nil, fmt.Errorf("failed to sign access token: %w", err)
 	}
 
-	// Generate refresh token
-	claims.TokenType = "refresh"
-	claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Hour * 24 * 30))
-	refreshToken, err := j.GenerateToken(&claims)
+	// Generate refresh token from a copy, the access token keeps a pointer to
+	// its claims. Its own jti keeps it unique among the tokens of its session,
+	// which are all kept after rotation to detect reuse.
+	refreshClaims := claims
+	refreshClaims.TokenType = "refresh"
+	refreshClaims.Permissions = nil
+	refreshClaims.ID = uuid.NewString()
+	refreshClaims.ExpiresAt = expiresAt(j.config.JwtRefreshTokenTTL)
+	refreshToken, err := j.GenerateToken(&refreshClaims)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign refresh token: %w", err)
 	}
@@ -92,18 +189,61 @@ func (j *JwtManager) GenerateTokenPair(userID uuid.UUID) (*TokenPair, error) {
 	}, nil
 }
 
+// GenerateChallengeToken issues a short-lived token proving that userID
+// signed in with their password, to be exchanged for a token pair once the
+// second factor is verified.
+func (j *JwtManager) GenerateChallengeToken(userID uuid.UUID) (*jwt.Token, error) {
+	now := time.Now()
+	return j.GenerateToken(&CustomClaims{
+		TokenType: "mfa_challenge",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userID.String(),
+			Issuer:    j.issuer,
+			Audience:  j.audience(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.config.MFAChallengeTTL)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	})
+}
+
+func (j *JwtManager) audience() jwt.ClaimStrings {
+	if j.config.JwtAudience == "" {
+		return nil
+	}
+	return jwt.ClaimStrings{j.config.JwtAudience}
+}
+
 func (j *JwtManager) GenerateToken(claims *CustomClaims) (*jwt.Token, error) {
-	jwtToken := jwt.NewWithClaims(signingMethod, claims)
+	jwtToken := jwt.NewWithClaims(j.signingKey.method, claims)
+	if j.signingKey.id != "" {
+		jwtToken.Header["kid"] = j.signingKey.id
+	}
 
-	key := []byte(j.config.JwtSecret)
 	var err error
-	jwtToken.Raw, err = jwtToken.SignedString(key)
+	jwtToken.Raw, err = jwtToken.SignedString(j.signingKey.private)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign %s token: %w", claims.TokenType, err)
 	}
 	return jwtToken, nil
 }
 
+// JWKS returns the public keys tokens can be verified with. It is empty when
+// tokens are signed with a shared secret.
+func (j *JwtManager) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range j.verificationKeys {
+		if jwk, ok := key.jwk(); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	slices.SortFunc(jwks.Keys, func(a, b JWK) int {
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+	return jwks
+}
+
 func (j *JwtManager) GetUserIDFromToken(token *jwt.Token) (uuid.UUID, error) {
 	subject, err := token.Claims.GetSubject()
 	if err != nil {
@@ -117,3 +257,83 @@ func (j *JwtManager) GetUserIDFromToken(token *jwt.Token) (uuid.UUID, error) {
 
 	return userID, nil
 }
+
+func (j *JwtManager) GetSessionIDFromToken(token *jwt.Token) (uuid.UUID, error) {
+	var sessionID string
+	switch claims := token.Claims.(type) {
+	case jwt.MapClaims:
+		sessionID, _ = claims["sid"].(string)
+	case *CustomClaims:
+		sessionID = claims.SessionID
+	}
+	if sessionID == "" {
+		return uuid.Nil, fmt.Errorf("token has no session id")
+	}
+
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to convert session id to UUID: %w", err)
+	}
+
+	return id, nil
+}
+
+func (j *JwtManager) GetTokenIDFromToken(token *jwt.Token) (uuid.UUID, error) {
+	var tokenID string
+	switch claims := token.Claims.(type) {
+	case jwt.MapClaims:
+		tokenID, _ = claims["jti"].(string)
+	case *CustomClaims:
+		tokenID = claims.ID
+	}
+	if tokenID == "" {
+		return uuid.Nil, fmt.Errorf("token has no token id")
+	}
+
+	id, err := uuid.Parse(tokenID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to convert token id to UUID: %w", err)
+	}
+
+	return id, nil
+}
+
+func (j *JwtManager) GetPermissionsFromToken(token *jwt.Token) []string {
+	switch claims := token.Claims.(type) {
+	case jwt.MapClaims:
+		values, _ := claims["perms"].([]interface{})
+		permissions := make([]string, 0, len(values))
+		for _, value := range values {
+			if permission, ok := value.(string); ok {
+				permissions = append(permissions, permission)
+			}
+		}
+		return permissions
+	case *CustomClaims:
+		return claims.Permissions
+	}
+	return nil
+}
+
+func (j *JwtManager) GetAMRFromToken(token *jwt.Token) []string {
+	switch claims := token.Claims.(type) {
+	case jwt.MapClaims:
+		values, _ := claims["amr"].([]interface{})
+		amr := make([]string, 0, len(values))
+		for _, value := range values {
+			if method, ok := value.(string); ok {
+				amr = append(amr, method)
+			}
+		}
+		return amr
+	case *CustomClaims:
+		return claims.AMR
+	}
+	return nil
+}
+
+// IsMultiFactor reports whether token was issued to a session signed in with
+// a second factor.
+func (j *JwtManager) IsMultiFactor(token *jwt.Token) bool {
+	return slices.Contains(j.GetAMRFromToken(token), AMRMultiFactor)
+}
@@ -1,6 +1,16 @@
 package apiserver_test
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -16,12 +26,14 @@ var _ = Describe("JwtManager", Ordered, func() {
 
 	BeforeAll(func() {
 		conf := config.GetConfig()
-		jwtManager = apiserver.NewJwtManager(conf)
+		var err error
+		jwtManager, err = apiserver.NewJwtManager(conf)
+		Expect(err).NotTo(HaveOccurred())
 	})
 
 	It("should generate token pair", func() {
 		userID := uuid.New()
-		tokenPair, err := jwtManager.GenerateTokenPair(userID)
+		tokenPair, err := jwtManager.GenerateTokenPair(userID, uuid.New(), time.Time{}, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(tokenPair.AccessToken).NotTo(BeNil())
@@ -30,7 +42,7 @@ var _ = Describe("JwtManager", Ordered, func() {
 
 	It("should parse token", func() {
 		userID := uuid.New()
-		tokenPair, err := jwtManager.GenerateTokenPair(userID)
+		tokenPair, err := jwtManager.GenerateTokenPair(userID, uuid.New(), time.Time{}, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 
 		accessToken, err := jwtManager.Parse(tokenPair.AccessToken.Raw)
@@ -44,7 +56,7 @@ var _ = Describe("JwtManager", Ordered, func() {
 
 	It("should create token for user", func() {
 		userID := uuid.New()
-		tokenPair, err := jwtManager.GenerateTokenPair(userID)
+		tokenPair, err := jwtManager.GenerateTokenPair(userID, uuid.New(), time.Time{}, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 
 		subject, err := tokenPair.AccessToken.Claims.GetSubject()
@@ -56,6 +68,125 @@ var _ = Describe("JwtManager", Ordered, func() {
 		Expect(subject).To(Equal(userID.String()))
 	})
 
+	It("should create token for session", func() {
+		sessionID := uuid.New()
+		tokenPair, err := jwtManager.GenerateTokenPair(uuid.New(), sessionID, time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken, err := jwtManager.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		id, err := jwtManager.GetSessionIDFromToken(accessToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal(sessionID))
+
+		refreshToken, err := jwtManager.Parse(tokenPair.RefreshToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		id, err = jwtManager.GetSessionIDFromToken(refreshToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal(sessionID))
+	})
+
+	It("should carry the authentication methods on both tokens", func() {
+		amr := []string{apiserver.AMRPassword, apiserver.AMRMultiFactor}
+		tokenPair, err := jwtManager.GenerateTokenPair(uuid.New(), uuid.New(), time.Time{}, nil, amr)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, token := range []string{tokenPair.AccessToken.Raw, tokenPair.RefreshToken.Raw} {
+			parsed, err := jwtManager.Parse(token)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jwtManager.GetAMRFromToken(parsed)).To(Equal(amr))
+			Expect(jwtManager.IsMultiFactor(parsed)).To(BeTrue())
+		}
+
+		tokenPair, err = jwtManager.GenerateTokenPair(uuid.New(), uuid.New(), time.Time{}, nil, []string{apiserver.AMRPassword})
+		Expect(err).NotTo(HaveOccurred())
+		parsed, err := jwtManager.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jwtManager.IsMultiFactor(parsed)).To(BeFalse())
+	})
+
+	It("should carry permissions on the access token only", func() {
+		permissions := []string{"users:read", "users:write"}
+		tokenPair, err := jwtManager.GenerateTokenPair(uuid.New(), uuid.New(), time.Time{}, permissions, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken, err := jwtManager.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jwtManager.GetPermissionsFromToken(accessToken)).To(Equal(permissions))
+
+		refreshToken, err := jwtManager.Parse(tokenPair.RefreshToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jwtManager.GetPermissionsFromToken(refreshToken)).To(BeEmpty())
+	})
+
+	It("should give each token its own ID", func() {
+		tokenPair, err := jwtManager.GenerateTokenPair(uuid.New(), uuid.New(), time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken, err := jwtManager.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		accessID, err := jwtManager.GetTokenIDFromToken(accessToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		refreshToken, err := jwtManager.Parse(tokenPair.RefreshToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		refreshID, err := jwtManager.GetTokenIDFromToken(refreshToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(accessID).NotTo(Equal(refreshID))
+	})
+
+	It("should generate challenge tokens", func() {
+		userID := uuid.New()
+		challengeToken, err := jwtManager.GenerateChallengeToken(userID)
+		Expect(err).NotTo(HaveOccurred())
+
+		token, err := jwtManager.Parse(challengeToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jwtManager.IsChallengeToken(token)).To(BeTrue())
+		Expect(jwtManager.IsAccessToken(token)).To(BeFalse())
+
+		id, err := jwtManager.GetUserIDFromToken(token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal(userID))
+	})
+
+	It("should not outlive the session", func() {
+		sessionExpiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		tokenPair, err := jwtManager.GenerateTokenPair(uuid.New(), uuid.New(), sessionExpiresAt, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		expiresAt, err := tokenPair.AccessToken.Claims.GetExpirationTime()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expiresAt.Time).To(BeTemporally("<", sessionExpiresAt))
+
+		expiresAt, err = tokenPair.RefreshToken.Claims.GetExpirationTime()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expiresAt.Time).To(BeTemporally("==", sessionExpiresAt))
+	})
+
+	It("should reject tokens for another issuer or audience", func() {
+		conf := *config.GetConfig()
+		conf.JwtIssuer = "https://auth.example.com"
+		conf.JwtAudience = "reports"
+		issuer, err := apiserver.NewJwtManager(&conf)
+		Expect(err).NotTo(HaveOccurred())
+
+		tokenPair, err := issuer.GenerateTokenPair(uuid.New(), uuid.New(), time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = issuer.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = jwtManager.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).Should(MatchError(ContainSubstring("token has invalid issuer")))
+
+		conf.JwtAudience = "billing"
+		verifier, err := apiserver.NewJwtManager(&conf)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = verifier.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).Should(MatchError(ContainSubstring("token has invalid audience")))
+	})
+
 	It("should fail to parse expired token", func() {
 		now := time.Now()
 
@@ -76,3 +207,95 @@ var _ = Describe("JwtManager", Ordered, func() {
 		Expect(err).Should(MatchError(ContainSubstring("token is expired")))
 	})
 })
+
+var _ = Describe("JwtManager with asymmetric keys", Ordered, func() {
+	writeKeys := func(dir, name string, private crypto.Signer) (string, string) {
+		privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+		Expect(err).NotTo(HaveOccurred())
+		publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+		Expect(err).NotTo(HaveOccurred())
+
+		privatePath := filepath.Join(dir, name+".pem")
+		publicPath := filepath.Join(dir, name+".pub.pem")
+		Expect(os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600)).To(Succeed())
+		Expect(os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600)).To(Succeed())
+		return privatePath, publicPath
+	}
+
+	// Signing keys by algorithm
+	keys := map[string]crypto.Signer{}
+
+	BeforeAll(func() {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		keys["RS256"], keys["ES256"], keys["EdDSA"] = rsaKey, ecKey, edKey
+	})
+
+	DescribeTable("should sign and verify tokens with a kid header",
+		func(alg, kty string) {
+			privatePath, _ := writeKeys(GinkgoT().TempDir(), "current", keys[alg])
+			conf := *config.GetConfig()
+			conf.JwtSigningKeyFile = privatePath
+
+			jwtManager, err := apiserver.NewJwtManager(&conf)
+			Expect(err).NotTo(HaveOccurred())
+
+			tokenPair, err := jwtManager.GenerateTokenPair(uuid.New(), uuid.New(), time.Time{}, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tokenPair.AccessToken.Header["kid"]).To(Equal("current"))
+			Expect(tokenPair.AccessToken.Header["alg"]).To(Equal(alg))
+
+			_, err = jwtManager.Parse(tokenPair.AccessToken.Raw)
+			Expect(err).NotTo(HaveOccurred())
+
+			jwks := jwtManager.JWKS()
+			Expect(jwks.Keys).To(HaveLen(1))
+			Expect(jwks.Keys[0].KeyID).To(Equal("current"))
+			Expect(jwks.Keys[0].KeyType).To(Equal(kty))
+			Expect(jwks.Keys[0].Algorithm).To(Equal(alg))
+		},
+		Entry("RS256", "RS256", "RSA"),
+		Entry("ES256", "ES256", "EC"),
+		Entry("EdDSA", "EdDSA", "OKP"),
+	)
+
+	It("should verify tokens signed with a retired key", func() {
+		dir := GinkgoT().TempDir()
+		oldPrivatePath, oldPublicPath := writeKeys(dir, "old", keys["ES256"])
+		newPrivatePath, _ := writeKeys(dir, "new", keys["EdDSA"])
+
+		oldConf := *config.GetConfig()
+		oldConf.JwtSigningKeyFile = oldPrivatePath
+		oldManager, err := apiserver.NewJwtManager(&oldConf)
+		Expect(err).NotTo(HaveOccurred())
+
+		tokenPair, err := oldManager.GenerateTokenPair(uuid.New(), uuid.New(), time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		newConf := *config.GetConfig()
+		newConf.JwtSigningKeyFile = newPrivatePath
+		newManager, err := apiserver.NewJwtManager(&newConf)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = newManager.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).Should(MatchError(ContainSubstring("unknown key id")))
+
+		newConf.JwtVerificationKeyFiles = []string{oldPublicPath}
+		newManager, err = apiserver.NewJwtManager(&newConf)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = newManager.Parse(tokenPair.AccessToken.Raw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(newManager.JWKS().Keys).To(HaveLen(2))
+	})
+
+	It("should not publish a shared secret", func() {
+		jwtManager, err := apiserver.NewJwtManager(config.GetConfig())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jwtManager.JWKS().Keys).To(BeEmpty())
+	})
+})
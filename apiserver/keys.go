@@ -0,0 +1,195 @@
+package apiserver
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// verificationKey is a public key, or for HS256 a shared secret, that tokens
+// with a matching kid header are verified with.
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	key    any
+}
+
+type signingKey struct {
+	verificationKey
+	private any
+}
+
+// keyIDFromPath derives a key ID from a key file name, up to its first dot,
+// so that keys/2025-01.pem and keys/2025-01.pub.pem share the kid 2025-01.
+func keyIDFromPath(path string) string {
+	id, _, _ := strings.Cut(filepath.Base(path), ".")
+	return id
+}
+
+func loadSigningKey(path string) (signingKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	var private crypto.Signer
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return signingKey{}, fmt.Errorf("failed to parse private key %s: %w", path, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return signingKey{}, fmt.Errorf("unsupported private key type %T in %s", key, path)
+		}
+		private = signer
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		return signingKey{}, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return signingKey{}, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+
+	method, err := signingMethodFor(private.Public())
+	if err != nil {
+		return signingKey{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return signingKey{
+		verificationKey: verificationKey{
+			id:     keyIDFromPath(path),
+			method: method,
+			key:    private.Public(),
+		},
+		private: private,
+	}, nil
+}
+
+func loadVerificationKey(path string) (verificationKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return verificationKey{}, err
+	}
+
+	var public any
+	switch block.Type {
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("failed to parse public key %s: %w", path, err)
+	}
+
+	method, err := signingMethodFor(public)
+	if err != nil {
+		return verificationKey{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return verificationKey{
+		id:     keyIDFromPath(path),
+		method: method,
+		key:    public,
+	}, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	return block, nil
+}
+
+func signingMethodFor(public any) (jwt.SigningMethod, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("unsupported elliptic curve %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", public)
+	}
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k verificationKey) jwk() (JWK, bool) {
+	jwk := JWK{
+		KeyID:     k.id,
+		Use:       "sig",
+		Algorithm: k.method.Alg(),
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+	switch key := k.key.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(key.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = key.Curve.Params().Name
+		jwk.X = encode(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = encode(key.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(key)
+	default:
+		// Shared secrets are never published.
+		return JWK{}, false
+	}
+	return jwk, true
+}
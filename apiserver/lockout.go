@@ -0,0 +1,39 @@
+package apiserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/talvor/asyncapi/dto"
+)
+
+// SigninThrottle decides how long an account stays locked after a failed
+// sign-in. Each failure doubles the delay before the next attempt is
+// accepted, starting at BaseDelay, until Threshold failures lock the account
+// for LockoutDuration.
+type SigninThrottle struct {
+	Threshold       int
+	BaseDelay       time.Duration
+	LockoutDuration time.Duration
+}
+
+// LockedUntil returns the time until which an account with failedAttempts
+// consecutive failures, the latest at now, is locked.
+func (t SigninThrottle) LockedUntil(failedAttempts int, now time.Time) time.Time {
+	if failedAttempts >= t.Threshold {
+		return now.Add(t.LockoutDuration)
+	}
+
+	delay := t.BaseDelay
+	for i := 1; i < failedAttempts && delay < t.LockoutDuration; i++ {
+		delay *= 2
+	}
+	return now.Add(min(delay, t.LockoutDuration))
+}
+
+// dummyPasswordHash is compared against when the email is unknown so that
+// signin takes as long as for a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := dto.HashPassword("dummy-password")
+	return hash
+})
@@ -0,0 +1,38 @@
+package apiserver_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/apiserver"
+)
+
+var _ = Describe("SigninThrottle", func() {
+	throttle := apiserver.SigninThrottle{
+		Threshold:       5,
+		BaseDelay:       time.Second,
+		LockoutDuration: 15 * time.Minute,
+	}
+	now := time.Now()
+
+	It("should double the delay on every failure", func() {
+		Expect(throttle.LockedUntil(1, now)).To(Equal(now.Add(time.Second)))
+		Expect(throttle.LockedUntil(2, now)).To(Equal(now.Add(2 * time.Second)))
+		Expect(throttle.LockedUntil(4, now)).To(Equal(now.Add(8 * time.Second)))
+	})
+
+	It("should lock the account once the threshold is reached", func() {
+		Expect(throttle.LockedUntil(5, now)).To(Equal(now.Add(15 * time.Minute)))
+		Expect(throttle.LockedUntil(50, now)).To(Equal(now.Add(15 * time.Minute)))
+	})
+
+	It("should never delay longer than the lockout", func() {
+		throttle := apiserver.SigninThrottle{
+			Threshold:       100,
+			BaseDelay:       time.Minute,
+			LockoutDuration: 15 * time.Minute,
+		}
+		Expect(throttle.LockedUntil(10, now)).To(Equal(now.Add(15 * time.Minute)))
+	})
+})
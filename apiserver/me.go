@@ -0,0 +1,145 @@
+package apiserver
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/store"
+)
+
+type UserResponse struct {
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	EmailVerified bool      `json:"email_verified"`
+	TwoFactor     bool      `json:"two_factor_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func newUserResponse(user *dto.User) UserResponse {
+	return UserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		Name:          user.Name,
+		EmailVerified: user.IsEmailVerified(),
+		TwoFactor:     user.IsTOTPEnabled(),
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+func (s *APIServer) getMeHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		resp := newUserResponse(currentUser(c))
+		if err := encode(APIResponse[UserResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// UpdateMeRequest holds the profile fields to change, fields left out are
+// kept as they are.
+type UpdateMeRequest struct {
+	Name *string `json:"name"`
+}
+
+func (r UpdateMeRequest) Validate() error {
+	if r.Name != nil && len(*r.Name) > 200 {
+		return errors.New("name must be at most 200 characters")
+	}
+	return nil
+}
+
+func (s *APIServer) updateMeHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[UpdateMeRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		user := currentUser(c)
+		if req.Name != nil {
+			user, err = s.store.Users.UpdateProfile(c.UserContext(), user.ID, *req.Name)
+			if err != nil {
+				return NewErrWithStatus(fiber.StatusInternalServerError, err)
+			}
+		}
+
+		resp := newUserResponse(user)
+		if err := encode(APIResponse[UserResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+func (r ChangePasswordRequest) Validate() error {
+	if r.CurrentPassword == "" {
+		return errors.New("current_password is required")
+	}
+	if r.NewPassword == "" {
+		return errors.New("new_password is required")
+	}
+	return nil
+}
+
+func (s *APIServer) changePasswordHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[ChangePasswordRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		user := currentUser(c)
+		if err := s.verifyPassword(c, user, req.CurrentPassword); err != nil {
+			if e, ok := err.(*ErrWithStatus); ok && e.status == fiber.StatusBadRequest {
+				s.audit(c, failedUserEvent(dto.AuditPasswordChange, user.ID, "invalid_password"))
+			}
+			return err
+		}
+
+		if _, err := s.store.Users.UpdatePassword(c.UserContext(), user.ID, req.NewPassword); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		sessionID, _ := c.Locals("session_id").(uuid.UUID)
+		if err := s.revokeOtherSessions(c.UserContext(), user.ID, sessionID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, userEvent(dto.AuditPasswordChange, user.ID))
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully changed password"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// verifyPassword checks the password a signed-in user confirms a sensitive
+// action with. Wrong passwords count towards the signin lockout, so that a
+// stolen access token cannot be used to guess the password.
+func (s *APIServer) verifyPassword(c *fiber.Ctx, user *dto.User, password string) error {
+	if _, err := s.store.Users.ClaimSigninAttempt(c.UserContext(), user.ID, s.signinThrottle, time.Now()); err != nil {
+		if errors.Is(err, store.ErrUserLocked) {
+			return NewErrWithStatus(fiber.StatusTooManyRequests, err)
+		}
+		return NewErrWithStatus(fiber.StatusInternalServerError, err)
+	}
+
+	if err := user.ComparePassword(password); err != nil {
+		return NewErrWithStatus(fiber.StatusBadRequest, errors.New("password is incorrect"))
+	}
+
+	if _, err := s.store.Users.ResetFailedSignins(c.UserContext(), user.ID); err != nil {
+		return NewErrWithStatus(fiber.StatusInternalServerError, err)
+	}
+	return nil
+}
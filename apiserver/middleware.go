@@ -1,55 +1,283 @@
 package apiserver
 
 import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/logging"
+	"github.com/talvor/asyncapi/ratelimit"
 	"github.com/talvor/asyncapi/store"
 )
 
-func AuthMiddleware(jwtManager *JwtManager, userStore *store.UserStore) func(c *fiber.Ctx) error {
+// AuthMiddleware authenticates the request with either a Bearer access token
+// or an API key, given as "Authorization: ApiKey <key>" or in the X-API-Key
+// header, and loads the user into c.Locals("user"). For API keys the key is
+// also stored in c.Locals("api_key"), for RequireScope. For access tokens the
+// permissions claim is stored in c.Locals("permissions"), for
+// RequirePermission, and whether the session passed a second factor in
+// c.Locals("mfa"), for RequireTwoFactor; API keys carry neither.
+func AuthMiddleware(jwtManager *JwtManager, userStore *store.UserStore, denylist *AccessTokenDenylist, apiKeyStore *store.APIKeyStore) func(c *fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
+		c.SetUserContext(logging.With(c.UserContext(), logging.KeyRoute, c.Route().Path))
+
 		sendUnauthorized := func(message string) error {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"status": "fail", "message": message})
 		}
 
-		var tokenString string
-		authorization := c.Get("Authorization")
+		var userID uuid.UUID
+		if key := apiKeyFromRequest(c); key != "" {
+			apiKey, err := authenticateAPIKey(c.UserContext(), apiKeyStore, key)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "failed to authenticate api key", "error", err)
+				return sendUnauthorized("You are not logged in")
+			}
+			userID = apiKey.UserID
+			c.Locals("api_key", apiKey)
+		} else {
+			tokenString, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+			if !ok || tokenString == "" {
+				return sendUnauthorized("You are not logged in")
+			}
 
-		if strings.HasPrefix(authorization, "Bearer ") {
-			tokenString = strings.TrimPrefix(authorization, "Bearer ")
-		}
+			parsedToken, err := jwtManager.Parse(tokenString)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "failed to parse token", "error", err)
+				return sendUnauthorized("You are not logged in")
+			}
 
-		if tokenString == "" {
-			return sendUnauthorized("You are not logged in")
+			if !jwtManager.IsAccessToken(parsedToken) {
+				return sendUnauthorized("Not an access token")
+			}
+
+			// Tokens without a jti cannot be revoked, so they are not trusted.
+			jti, err := jwtManager.GetTokenIDFromToken(parsedToken)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "failed to get token id", "error", err)
+				return sendUnauthorized("You are not logged in")
+			}
+			revoked, err := denylist.IsRevoked(c.UserContext(), jti)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "failed to check access token denylist", "error", err)
+				return sendUnauthorized("You are not logged in")
+			}
+			if revoked {
+				return sendUnauthorized("You are not logged in")
+			}
+
+			userID, err = jwtManager.GetUserIDFromToken(parsedToken)
+			if err != nil {
+				slog.ErrorContext(c.UserContext(), "failed to convert subject to UUID", "error", err)
+				return sendUnauthorized("You are not logged in")
+			}
+
+			if sessionID, err := jwtManager.GetSessionIDFromToken(parsedToken); err == nil {
+				c.Locals("session_id", sessionID)
+			}
+			c.Locals("permissions", jwtManager.GetPermissionsFromToken(parsedToken))
+			c.Locals("mfa", jwtManager.IsMultiFactor(parsedToken))
 		}
 
-		parsedToken, err := jwtManager.Parse(tokenString)
+		user, err := userStore.ByID(c.UserContext(), userID)
 		if err != nil {
-			slog.Error("failed to parse token", "error", err)
+			slog.ErrorContext(c.UserContext(), "failed to get user by id", "error", err)
 			return sendUnauthorized("You are not logged in")
 		}
+		if user.IsDisabled() {
+			return sendUnauthorized("Your account has been disabled")
+		}
+
+		c.Locals("user", user)
+		c.SetUserContext(logging.With(c.UserContext(), logging.KeyUserID, user.ID))
+
+		return c.Next()
+	}
+
+}
+
+func apiKeyFromRequest(c *fiber.Ctx) string {
+	if key, ok := strings.CutPrefix(c.Get("Authorization"), "ApiKey "); ok {
+		return key
+	}
+	return c.Get("X-API-Key")
+}
+
+// authenticateAPIKey returns the stored key matching key, if it has not
+// expired, and records its use.
+func authenticateAPIKey(ctx context.Context, apiKeyStore *store.APIKeyStore, key string) (*dto.APIKey, error) {
+	prefix, secret, ok := dto.ParseAPIKey(key)
+	if !ok {
+		return nil, errors.New("malformed api key")
+	}
+
+	apiKey, err := apiKeyStore.ByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.HashedSecret), []byte(dto.HashSecretToken(secret))) != 1 {
+		return nil, fmt.Errorf("api key %s secret mismatch", apiKey.ID)
+	}
+	if apiKey.IsExpired(time.Now()) {
+		return nil, fmt.Errorf("api key %s expired at %s", apiKey.ID, apiKey.ExpiresAt)
+	}
+
+	if _, err := apiKeyStore.Touch(ctx, apiKey.ID); err != nil {
+		slog.ErrorContext(ctx, "failed to record api key use", "error", err, "api_key_id", apiKey.ID)
+	}
+	return apiKey, nil
+}
+
+// currentAPIKey returns the API key the request was authenticated with, or
+// nil for requests authenticated with an access token.
+func currentAPIKey(c *fiber.Ctx) *dto.APIKey {
+	apiKey, _ := c.Locals("api_key").(*dto.APIKey)
+	return apiKey
+}
+
+// RequireScope rejects requests authenticated with an API key lacking scope.
+// It must run after AuthMiddleware.
+func RequireScope(scope string) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if apiKey := currentAPIKey(c); apiKey != nil && !apiKey.HasScope(scope) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "fail", "message": "API key lacks scope " + scope})
+		}
+		return c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated with an API key, for account
+// management routes only the user themselves may use. It must run after
+// AuthMiddleware.
+func RequireSession() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if currentAPIKey(c) != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "fail", "message": "API keys cannot be used here"})
+		}
+		return c.Next()
+	}
+}
+
+// RequirePermission rejects requests whose access token lacks any of
+// permissions. It must run after AuthMiddleware.
+func RequirePermission(permissions ...string) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		granted, _ := c.Locals("permissions").([]string)
+		for _, permission := range permissions {
+			if !slices.Contains(granted, permission) {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "fail", "message": "Missing permission " + permission})
+			}
+		}
+		return c.Next()
+	}
+}
 
-		if !jwtManager.IsAccessToken(parsedToken) {
-			return sendUnauthorized("Not an access token")
+// ActiveOrgMiddleware selects the organization named by the X-Org-ID header,
+// if any, as the one the request acts in and stores the user's membership in
+// c.Locals("membership"). Users who are not members are rejected. It must run
+// after AuthMiddleware.
+func ActiveOrgMiddleware(orgStore *store.OrganizationStore) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		header := c.Get("X-Org-ID")
+		if header == "" {
+			return c.Next()
 		}
 
-		userID, err := jwtManager.GetUserIDFromToken(parsedToken)
+		orgID, err := uuid.Parse(header)
 		if err != nil {
-			slog.Error("failed to convert subject to UUID", "error", err)
-			return sendUnauthorized("You are not logged in")
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "fail", "message": "Invalid X-Org-ID header"})
 		}
 
-		user, err := userStore.ByID(c.Context(), userID)
+		membership, err := orgStore.Membership(c.UserContext(), orgID, currentUser(c).ID)
 		if err != nil {
-			slog.Error("failed to get user by id", "error", err)
-			return sendUnauthorized("You are not logged in")
+			if !errors.Is(err, sql.ErrNoRows) {
+				slog.ErrorContext(c.UserContext(), "failed to get membership", "error", err, "org_id", orgID)
+			}
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "fail", "message": "Not a member of this organization"})
 		}
 
-		c.Locals("user", user)
+		c.Locals("membership", membership)
+		c.SetUserContext(logging.With(c.UserContext(), logging.KeyOrgID, orgID))
+		return c.Next()
+	}
+}
+
+// currentMembership returns the membership of the user in the organization
+// selected by ActiveOrgMiddleware, or nil when none was selected.
+func currentMembership(c *fiber.Ctx) *dto.Membership {
+	membership, _ := c.Locals("membership").(*dto.Membership)
+	return membership
+}
 
+// RequireTwoFactor rejects users who have not enabled two-factor
+// authentication, and sessions that were not signed in with it. It must run
+// after AuthMiddleware.
+func RequireTwoFactor() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if user := currentUser(c); user == nil || !user.IsTOTPEnabled() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "fail", "message": "Two-factor authentication required"})
+		}
+		if mfa, _ := c.Locals("mfa").(bool); !mfa {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "fail", "message": "Sign in with two-factor authentication"})
+		}
 		return c.Next()
 	}
+}
+
+// RequireVerifiedEmail rejects users who have not verified their email
+// address. It must run after AuthMiddleware.
+func RequireVerifiedEmail() func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		if user := currentUser(c); user == nil || !user.IsEmailVerified() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"status": "fail", "message": "Email address not verified"})
+		}
+		return c.Next()
+	}
+}
+
+// RateLimitMiddleware limits requests to the route group named name. Requests
+// are keyed by the authenticated user when AuthMiddleware ran before it, and
+// by client IP otherwise. Limiter errors are logged and the request is let
+// through.
+func RateLimitMiddleware(limiter ratelimit.Store, name string, limit ratelimit.Limit) func(c *fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		key := name + ":ip:" + c.IP()
+		if user, ok := c.Locals("user").(*dto.User); ok {
+			key = name + ":user:" + user.ID.String()
+		}
+
+		result, err := limiter.Take(c.UserContext(), key, limit)
+		if err != nil {
+			slog.ErrorContext(c.UserContext(), "failed to take rate limit token", "error", err, "key", key)
+			return c.Next()
+		}
+
+		c.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(APIResponse[struct{}]{
+				Message: http.StatusText(fiber.StatusTooManyRequests),
+			})
+		}
+
+		return c.Next()
+	}
+}
 
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
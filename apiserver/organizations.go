@@ -0,0 +1,323 @@
+package apiserver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/mailer"
+	"github.com/talvor/asyncapi/store"
+)
+
+type OrganizationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MemberResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+func (r CreateOrganizationRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(r.Name) > 200 {
+		return errors.New("name must be at most 200 characters")
+	}
+	return nil
+}
+
+func (s *APIServer) createOrganizationHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[CreateOrganizationRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		org, err := s.store.Organizations.Create(c.UserContext(), req.Name, currentUser(c).ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := OrganizationResponse{ID: org.ID, Name: org.Name, Role: dto.OrgRoleAdmin, CreatedAt: org.CreatedAt}
+		if err := encode(APIResponse[OrganizationResponse]{Data: &resp}, fiber.StatusCreated, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) listOrganizationsHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		orgs, err := s.store.Organizations.ByUserID(c.UserContext(), currentUser(c).ID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := make([]OrganizationResponse, 0, len(orgs))
+		for _, org := range orgs {
+			resp = append(resp, OrganizationResponse{ID: org.ID, Name: org.Name, Role: org.Role, CreatedAt: org.CreatedAt})
+		}
+
+		if err := encode(APIResponse[[]OrganizationResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// orgMembership returns the membership of the current user in the
+// organization named by the id route parameter. Organizations the user is not
+// a member of are reported as not found, and when admin is set so are those
+// they do not administer.
+func (s *APIServer) orgMembership(c *fiber.Ctx, admin bool) (*dto.Membership, error) {
+	orgID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid organization id: %w", err))
+	}
+
+	membership, err := s.store.Organizations.Membership(c.UserContext(), orgID, currentUser(c).ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, NewErrWithStatus(fiber.StatusNotFound, err)
+		}
+		return nil, NewErrWithStatus(fiber.StatusInternalServerError, err)
+	}
+	if admin && !membership.IsAdmin() {
+		return nil, NewErrWithStatus(fiber.StatusForbidden, fmt.Errorf("user %s is not an admin of organization %s", membership.UserID, orgID))
+	}
+	return membership, nil
+}
+
+func (s *APIServer) listMembersHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		membership, err := s.orgMembership(c, false)
+		if err != nil {
+			return err
+		}
+
+		members, err := s.store.Organizations.Members(c.UserContext(), membership.OrgID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := make([]MemberResponse, 0, len(members))
+		for _, member := range members {
+			resp = append(resp, MemberResponse{
+				UserID:    member.UserID,
+				Email:     member.Email,
+				Name:      member.Name,
+				Role:      member.Role,
+				CreatedAt: member.CreatedAt,
+			})
+		}
+
+		if err := encode(APIResponse[[]MemberResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+func (r UpdateMemberRequest) Validate() error {
+	if !slices.Contains(dto.OrgRoles, r.Role) {
+		return fmt.Errorf("unknown role %q", r.Role)
+	}
+	return nil
+}
+
+func (s *APIServer) updateMemberHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		membership, err := s.orgMembership(c, true)
+		if err != nil {
+			return err
+		}
+
+		req, err := decode[UpdateMemberRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		userID, err := uuid.Parse(c.Params("user_id"))
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid user id: %w", err))
+		}
+
+		if _, err := s.store.Organizations.Membership(c.UserContext(), membership.OrgID, userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusNotFound, err)
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		result, err := s.store.Organizations.UpdateMemberRole(c.UserContext(), membership.OrgID, userID, req.Role)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return NewErrWithStatus(fiber.StatusConflict, errors.New("an organization must keep at least one admin"))
+		}
+		s.audit(c, memberEvent(dto.AuditOrgMemberUpdate, membership.OrgID, userID, req.Role))
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully updated member"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// removeMemberHandler removes a member from an organization. Admins may
+// remove anyone, other members only themselves.
+func (s *APIServer) removeMemberHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		membership, err := s.orgMembership(c, false)
+		if err != nil {
+			return err
+		}
+
+		userID, err := uuid.Parse(c.Params("user_id"))
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid user id: %w", err))
+		}
+		if userID != membership.UserID && !membership.IsAdmin() {
+			return NewErrWithStatus(fiber.StatusForbidden, fmt.Errorf("user %s is not an admin of organization %s", membership.UserID, membership.OrgID))
+		}
+
+		if _, err := s.store.Organizations.Membership(c.UserContext(), membership.OrgID, userID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusNotFound, err)
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		result, err := s.store.Organizations.RemoveMember(c.UserContext(), membership.OrgID, userID)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return NewErrWithStatus(fiber.StatusConflict, errors.New("an organization must keep at least one admin"))
+		}
+		s.audit(c, memberEvent(dto.AuditOrgMemberRemove, membership.OrgID, userID, ""))
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully removed member"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func (r CreateInvitationRequest) Validate() error {
+	if r.Email == "" {
+		return errors.New("email is required")
+	}
+	if _, err := NormalizeEmail(r.Email); err != nil {
+		return err
+	}
+	if !slices.Contains(dto.OrgRoles, r.Role) {
+		return fmt.Errorf("unknown role %q", r.Role)
+	}
+	return nil
+}
+
+// createInvitationHandler mails an invitation to join the organization. The
+// recipient accepts it once signed in with the invited email address.
+func (s *APIServer) createInvitationHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		membership, err := s.orgMembership(c, true)
+		if err != nil {
+			return err
+		}
+
+		req, err := decode[CreateInvitationRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+		if req.Email, err = NormalizeEmail(req.Email); err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		token, err := dto.NewSecretToken()
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		expiresAt := time.Now().Add(s.config.InvitationTTL)
+		if _, err := s.store.Organizations.CreateInvitation(c.UserContext(), membership.OrgID, req.Email, req.Role, membership.UserID, token, expiresAt); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if err := s.mailer.Send(c.UserContext(), mailer.Message{
+			To:      req.Email,
+			Subject: "You have been invited to an organization",
+			Body: fmt.Sprintf("%s invited you to join their organization. Sign in with this email address and use the following token to accept, it expires at %s:\n\n%s\n",
+				currentUser(c).Email, expiresAt.UTC().Format(time.RFC1123), token),
+		}); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if err := encode(APIResponse[struct{}]{Message: "invitation sent"}, fiber.StatusCreated, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+func (r AcceptInvitationRequest) Validate() error {
+	if r.Token == "" {
+		return errors.New("token is required")
+	}
+	return nil
+}
+
+func (s *APIServer) acceptInvitationHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[AcceptInvitationRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		user := currentUser(c)
+		if _, err := s.store.Organizations.AcceptInvitation(c.UserContext(), req.Token, user.Email, user.ID); err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return NewErrWithStatus(fiber.StatusBadRequest, errors.New("invalid or expired token"))
+			case errors.Is(err, store.ErrAlreadyMember):
+				return NewErrWithStatus(fiber.StatusConflict, errors.New("you are already a member of this organization"))
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully joined organization"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,181 @@
+package apiserver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/store"
+)
+
+const (
+	defaultReportsLimit = 50
+	maxReportsLimit     = 100
+)
+
+type ReportResponse struct {
+	ID                   uuid.UUID  `json:"id"`
+	UserID               uuid.UUID  `json:"user_id"`
+	OrgID                *uuid.UUID `json:"org_id"`
+	ReportType           string     `json:"report_type"`
+	DownloadURL          *string    `json:"download_url"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at"`
+	ErrorMessage         *string    `json:"error_message"`
+	CreatedAt            time.Time  `json:"created_at"`
+	StartedAt            *time.Time `json:"started_at"`
+	CompletedAt          *time.Time `json:"completed_at"`
+	FailedAt             *time.Time `json:"failed_at"`
+	DeletedAt            *time.Time `json:"deleted_at,omitempty"`
+}
+
+func newReportResponse(report *dto.Report) ReportResponse {
+	return ReportResponse{
+		ID:                   report.ID,
+		UserID:               report.UserID,
+		OrgID:                report.OrgID,
+		ReportType:           report.ReportType,
+		DownloadURL:          report.DownloadURL,
+		DownloadURLExpiresAt: report.DownloadURLExpiresAt,
+		ErrorMessage:         report.ErrorMessage,
+		CreatedAt:            report.CreatedAt,
+		StartedAt:            report.StartedAt,
+		CompletedAt:          report.CompletedAt,
+		FailedAt:             report.FailedAt,
+		DeletedAt:            report.DeletedAt,
+	}
+}
+
+// reportScope returns the reports visible to the request: those of the
+// organization selected with ActiveOrgMiddleware, or else the user's own.
+func reportScope(c *fiber.Ctx) store.ReportScope {
+	scope := store.ReportScope{UserID: currentUser(c).ID}
+	if membership := currentMembership(c); membership != nil {
+		scope.OrgID = &membership.OrgID
+	}
+	return scope
+}
+
+func (s *APIServer) listReportsHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", defaultReportsLimit)
+		if limit < 1 || limit > maxReportsLimit {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxReportsLimit))
+		}
+
+		reports, err := s.store.Reports.List(c.UserContext(), reportScope(c), limit)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := make([]ReportResponse, 0, len(reports))
+		for i := range reports {
+			resp = append(resp, newReportResponse(&reports[i]))
+		}
+
+		if err := encode(APIResponse[[]ReportResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) getReportHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		reportID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid report id: %w", err))
+		}
+
+		report, err := s.store.Reports.ByScope(c.UserContext(), reportScope(c), reportID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusNotFound, err)
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := newReportResponse(report)
+		if err := encode(APIResponse[ReportResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// deleteReportHandler deletes a report, which can be restored during the
+// restore period. Reports shared with an organization can only be deleted by
+// their creator or an admin of the organization.
+func (s *APIServer) deleteReportHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		reportID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid report id: %w", err))
+		}
+
+		scope := reportScope(c)
+		report, err := s.store.Reports.ByScope(c.UserContext(), scope, reportID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusNotFound, err)
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if membership := currentMembership(c); membership != nil && !membership.IsAdmin() && report.UserID != currentUser(c).ID {
+			return NewErrWithStatus(fiber.StatusForbidden, fmt.Errorf("user %s cannot delete report %s of user %s", currentUser(c).ID, report.ID, report.UserID))
+		}
+
+		report, err = s.store.Reports.SoftDelete(c.UserContext(), scope, reportID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusNotFound, err)
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := newReportResponse(report)
+		if err := encode(APIResponse[ReportResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+func (s *APIServer) restoreReportHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		reportID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, fmt.Errorf("invalid report id: %w", err))
+		}
+
+		scope := reportScope(c)
+		deletedAfter := time.Now().Add(-s.config.ReportRestorePeriod)
+		report, err := s.store.Reports.DeletedByScope(c.UserContext(), scope, reportID, deletedAfter)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusNotFound, err)
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if membership := currentMembership(c); membership != nil && !membership.IsAdmin() && report.UserID != currentUser(c).ID {
+			return NewErrWithStatus(fiber.StatusForbidden, fmt.Errorf("user %s cannot restore report %s of user %s", currentUser(c).ID, report.ID, report.UserID))
+		}
+
+		report, err = s.store.Reports.Restore(c.UserContext(), scope, reportID, deletedAfter)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewErrWithStatus(fiber.StatusNotFound, err)
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		resp := newReportResponse(report)
+		if err := encode(APIResponse[ReportResponse]{Data: &resp}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
@@ -1,50 +1,246 @@
 package apiserver
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/talvor/asyncapi/blobstore"
 	"github.com/talvor/asyncapi/config"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/logging"
+	"github.com/talvor/asyncapi/mailer"
+	"github.com/talvor/asyncapi/metrics"
+	"github.com/talvor/asyncapi/ratelimit"
 	"github.com/talvor/asyncapi/store"
+	"github.com/talvor/asyncapi/tracing"
 )
 
 type APIServer struct {
-	config     *config.Config
-	store      *store.Store
-	jwtManager *JwtManager
+	config         *config.Config
+	store          *store.Store
+	jwtManager     *JwtManager
+	denylist       *AccessTokenDenylist
+	mailer         mailer.Mailer
+	blobs          blobstore.BlobStore
+	signinThrottle SigninThrottle
+	signupPolicy   SignupPolicy
 }
 
-func New(config *config.Config, store *store.Store) *APIServer {
-	jwtManager := NewJwtManager(config)
+func New(config *config.Config, store *store.Store) (*APIServer, error) {
+	jwtManager, err := NewJwtManager(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dto.SetPasswordHashing(dto.Argon2Params{
+		Memory:      config.PasswordArgon2Memory,
+		Iterations:  config.PasswordArgon2Iterations,
+		Parallelism: config.PasswordArgon2Parallelism,
+		SaltLength:  dto.DefaultArgon2Params.SaltLength,
+		KeyLength:   dto.DefaultArgon2Params.KeyLength,
+	}, config.PasswordPepper)
+
+	mailer, err := mailer.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	blobs, err := blobstore.New(context.Background(), config)
+	if err != nil && !errors.Is(err, blobstore.ErrNotConfigured) {
+		return nil, err
+	}
+
+	signupPolicy, err := NewSignupPolicy(config.SignupPolicy, config.SignupAllowedDomains)
+	if err != nil {
+		return nil, err
+	}
+
 	return &APIServer{
 		config:     config,
 		store:      store,
 		jwtManager: jwtManager,
-	}
+		denylist:   NewAccessTokenDenylist(store.RevokedTokens, config.JwtDenylistCacheTTL),
+		mailer:     mailer,
+		blobs:      blobs,
+		signinThrottle: SigninThrottle{
+			Threshold:       config.SigninLockoutThreshold,
+			BaseDelay:       config.SigninBaseDelay,
+			LockoutDuration: config.SigninLockoutDuration,
+		},
+		signupPolicy: signupPolicy,
+	}, nil
 }
 
 func (s *APIServer) Start() error {
-	app := fiber.New()
+	limiter, err := s.rateLimitStore()
+	if err != nil {
+		return err
+	}
+	authLimit, err := ratelimit.ParseLimit(s.config.RateLimitAuth)
+	if err != nil {
+		return err
+	}
+	apiLimit, err := ratelimit.ParseLimit(s.config.RateLimitAPI)
+	if err != nil {
+		return err
+	}
+	go every(time.Minute, "rate limits", func(ctx context.Context) error {
+		// Buckets idle for longer than the longest period are full anyway.
+		return limiter.Cleanup(ctx, time.Now().Add(-max(authLimit.Period, apiLimit.Period)))
+	})
+	go every(time.Hour, "expired refresh tokens", func(ctx context.Context) error {
+		_, err := s.store.RefreshTokens.DeleteExpired(ctx, time.Now())
+		return err
+	})
+	go every(10*time.Minute, "access token denylist", s.denylist.Cleanup)
+	go every(time.Hour, "expired user tokens", func(ctx context.Context) error {
+		_, err := s.store.UserTokens.DeleteExpired(ctx, time.Now())
+		return err
+	})
+	go every(time.Hour, "expired invitations", func(ctx context.Context) error {
+		_, err := s.store.Organizations.DeleteExpiredInvitations(ctx, time.Now())
+		return err
+	})
+	go every(time.Hour, "deleted reports", func(ctx context.Context) error {
+		_, err := s.store.Reports.Purge(ctx, time.Now().Add(-s.config.ReportRestorePeriod))
+		return err
+	})
+
+	if s.blobs != nil {
+		go every(time.Minute, "deleted blobs", s.purgeBlobs)
+	} else {
+		slog.Warn("blob storage is not configured, outputs of deleted reports are kept")
+	}
+
+	go s.serveMetrics()
+
+	// Without the trusted proxy check Fiber reads ProxyHeader from any
+	// client, letting them pick the IP that rate limits are keyed by.
+	app := fiber.New(fiber.Config{
+		ProxyHeader:             s.config.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          s.config.TrustedProxies,
+		EnableIPValidation:      true,
+	})
 
 	app.Use(requestid.New())
-	app.Use(logger.New(logger.Config{
-		// For more options, see the Config section
-		Format:     "${time} ${locals:requestid} ${status} - ${method} ${path}\u200b\n",
-		TimeFormat: time.RFC3339,
-	}))
+	app.Use(metrics.Middleware())
+	app.Use(tracing.Middleware())
+	app.Use(logging.Middleware())
 
-	app.Get("/ping", AuthMiddleware(s.jwtManager, s.store.Users), s.ping())
+	app.Get("/.well-known/jwks.json", s.jwksHandler())
+	authenticated := AuthMiddleware(s.jwtManager, s.store.Users, s.denylist, s.store.APIKeys)
+	sessionOnly := RequireSession()
+	verified := func(c *fiber.Ctx) error { return c.Next() }
+	if s.signupPolicy.RequiresVerifiedEmail() {
+		verified = RequireVerifiedEmail()
+	}
+
+	app.Get("/ping", authenticated, verified, RateLimitMiddleware(limiter, "api", apiLimit), s.ping())
 
-	auth := app.Group("/auth")
+	auth := app.Group("/auth", RateLimitMiddleware(limiter, "auth", authLimit))
 	auth.Post("/signup", s.signupHandler())
 	auth.Post("/signin", s.signinHandler())
+	auth.Post("/signin/2fa", s.signinTwoFactorHandler())
 	auth.Post("/refresh", s.refreshTokenHandler())
+	auth.Post("/verify-email", s.verifyEmailHandler())
+	auth.Post("/verify-email/resend", authenticated, sessionOnly, s.resendEmailVerificationHandler())
+	auth.Post("/password-reset/request", s.requestPasswordResetHandler())
+	auth.Post("/password-reset/confirm", s.confirmPasswordResetHandler())
+	auth.Post("/signout", authenticated, sessionOnly, s.signoutHandler())
+	auth.Post("/signout-all", authenticated, sessionOnly, s.signoutAllHandler())
+	auth.Get("/sessions", authenticated, sessionOnly, s.listSessionsHandler())
+	auth.Delete("/sessions/:id", authenticated, sessionOnly, s.deleteSessionHandler())
+
+	me := app.Group("/me", authenticated, sessionOnly, verified, RateLimitMiddleware(limiter, "api", apiLimit))
+	me.Get("/", s.getMeHandler())
+	me.Patch("/", s.updateMeHandler())
+	me.Delete("/", s.deleteMeHandler())
+	me.Post("/export", s.exportMeHandler())
+	me.Post("/password", s.changePasswordHandler())
+	me.Post("/2fa/setup", s.setupTwoFactorHandler())
+	me.Post("/2fa/confirm", s.confirmTwoFactorHandler())
+	me.Delete("/2fa", s.disableTwoFactorHandler())
+	me.Post("/api-keys", s.createAPIKeyHandler())
+	me.Get("/api-keys", s.listAPIKeysHandler())
+	me.Delete("/api-keys/:id", s.deleteAPIKeyHandler())
+
+	orgs := app.Group("/orgs", authenticated, sessionOnly, verified, RateLimitMiddleware(limiter, "api", apiLimit))
+	orgs.Post("/", s.createOrganizationHandler())
+	orgs.Get("/", s.listOrganizationsHandler())
+	orgs.Post("/invitations/accept", s.acceptInvitationHandler())
+	orgs.Get("/:id/members", s.listMembersHandler())
+	orgs.Patch("/:id/members/:user_id", s.updateMemberHandler())
+	orgs.Delete("/:id/members/:user_id", s.removeMemberHandler())
+	orgs.Post("/:id/invitations", s.createInvitationHandler())
+
+	// Operators must use two-factor authentication.
+	admin := app.Group("/admin", authenticated, sessionOnly, verified, RequireTwoFactor(), RateLimitMiddleware(limiter, "api", apiLimit))
+	admin.Get("/users", RequirePermission(dto.PermissionUsersRead), s.adminListUsersHandler())
+	admin.Get("/users/:id", RequirePermission(dto.PermissionUsersRead), s.adminGetUserHandler())
+	admin.Post("/users/:id/disable", RequirePermission(dto.PermissionUsersWrite), s.adminDisableUserHandler())
+	admin.Post("/users/:id/enable", RequirePermission(dto.PermissionUsersWrite), s.adminEnableUserHandler())
+	admin.Post("/users/:id/unlock", RequirePermission(dto.PermissionUsersWrite), s.adminUnlockUserHandler())
+	admin.Post("/users/:id/signout", RequirePermission(dto.PermissionUsersWrite), s.adminSignoutUserHandler())
+	admin.Get("/reports", RequirePermission(dto.PermissionReportsReadAll), s.adminListReportsHandler())
+	admin.Post("/reports/:id/requeue", RequirePermission(dto.PermissionReportsWriteAll), s.adminRequeueReportHandler())
+	admin.Post("/reports/:id/fail", RequirePermission(dto.PermissionReportsWriteAll), s.adminFailReportHandler())
+	admin.Get("/audit-events", RequirePermission(dto.PermissionAuditRead), s.adminListAuditEventsHandler())
+	admin.Get("/audit-events/export", RequirePermission(dto.PermissionAuditRead), s.adminExportAuditEventsHandler())
+	admin.Post("/invite-codes", RequirePermission(dto.PermissionInvitesWrite), s.adminCreateInviteCodeHandler())
+	admin.Get("/invite-codes", RequirePermission(dto.PermissionInvitesRead), s.adminListInviteCodesHandler())
+	admin.Delete("/invite-codes/:id", RequirePermission(dto.PermissionInvitesWrite), s.adminDeleteInviteCodeHandler())
+
+	reports := app.Group("/reports", authenticated, verified, RateLimitMiddleware(limiter, "api", apiLimit), ActiveOrgMiddleware(s.store.Organizations))
+	reports.Get("/", RequireScope(dto.ScopeReportsRead), s.listReportsHandler())
+	reports.Get("/:id", RequireScope(dto.ScopeReportsRead), s.getReportHandler())
+	// Whatever the signup policy, unverified users cannot change reports.
+	reports.Delete("/:id", RequireScope(dto.ScopeReportsWrite), RequireVerifiedEmail(), s.deleteReportHandler())
+	reports.Post("/:id/restore", RequireScope(dto.ScopeReportsWrite), RequireVerifiedEmail(), s.restoreReportHandler())
 
 	host := net.JoinHostPort(s.config.APIHost, s.config.APIPort)
 	slog.Info("starting server", "host", host)
 	return app.Listen(net.JoinHostPort(s.config.APIHost, s.config.APIPort))
 }
+
+func (s *APIServer) rateLimitStore() (ratelimit.Store, error) {
+	switch s.config.RateLimitStore {
+	case "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		return s.store.RateLimits, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", s.config.RateLimitStore)
+	}
+}
+
+// every runs the cleanup job f every interval for the lifetime of the process.
+func every(interval time.Duration, name string, f func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := f(context.Background()); err != nil {
+			slog.Error("failed to clean up "+name, "error", err)
+		}
+	}
+}
+
+// serveMetrics serves GET /metrics on the metrics listener, apart from the
+// public API.
+func (s *APIServer) serveMetrics() {
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/metrics", metrics.Handler())
+
+	host := net.JoinHostPort(s.config.MetricsHost, s.config.MetricsPort)
+	slog.Info("starting metrics server", "host", host)
+	if err := app.Listen(host); err != nil {
+		slog.Error("metrics server stopped", "error", err)
+	}
+}
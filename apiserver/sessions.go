@@ -0,0 +1,100 @@
+package apiserver
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+)
+
+// sessionExpiresAt returns the time after which a session can no longer be
+// refreshed, or the zero time when sessions live as long as they are used.
+func (s *APIServer) sessionExpiresAt(session *dto.Session) time.Time {
+	if s.config.SessionMaxLifetime <= 0 {
+		return time.Time{}
+	}
+	return session.CreatedAt.Add(s.config.SessionMaxLifetime)
+}
+
+// startSession creates a session for user signing in with the request c and
+// the authentication methods amr, and issues its first token pair.
+func (s *APIServer) startSession(c *fiber.Ctx, user *dto.User, amr []string) (*TokenPair, error) {
+	session, err := s.store.Sessions.Create(c.UserContext(), user.ID, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.store.Roles.PermissionsByUserID(c.UserContext(), user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, session.ID, s.sessionExpiresAt(session), permissions, amr)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.store.RefreshTokens.Create(c.UserContext(), user.ID, session.ID, tokenPair.RefreshToken); err != nil {
+		return nil, err
+	}
+
+	if err := s.recordAccessToken(c.UserContext(), user.ID, session.ID, tokenPair); err != nil {
+		return nil, err
+	}
+	return tokenPair, nil
+}
+
+// recordAccessToken remembers the access token of tokenPair as the latest one
+// issued for a session, so that revoking the session also revokes it. The
+// access token it replaces is revoked, so a session only ever has one valid
+// access token.
+func (s *APIServer) recordAccessToken(ctx context.Context, userID, sessionID uuid.UUID, tokenPair *TokenPair) error {
+	if err := s.denylist.RevokeSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	jti, err := s.jwtManager.GetTokenIDFromToken(tokenPair.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	expiresAt, err := tokenPair.AccessToken.Claims.GetExpirationTime()
+	if err != nil {
+		return fmt.Errorf("failed to get expires at from token: %w", err)
+	}
+
+	_, err = s.store.Sessions.SetAccessToken(ctx, sessionID, jti, expiresAt.Time)
+	return err
+}
+
+// revokeSession denies the latest access token of a session and deletes the
+// session together with its refresh tokens.
+func (s *APIServer) revokeSession(ctx context.Context, userID, sessionID uuid.UUID) (sql.Result, error) {
+	if err := s.denylist.RevokeSession(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+	return s.store.Sessions.Delete(ctx, userID, sessionID)
+}
+
+// revokeUserSessions denies the latest access token of every session of a
+// user and deletes the sessions together with their refresh tokens.
+func (s *APIServer) revokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := s.denylist.RevokeUserSessions(ctx, userID); err != nil {
+		return err
+	}
+	_, err := s.store.Sessions.DeleteUserSessions(ctx, userID)
+	return err
+}
+
+// revokeOtherSessions is revokeUserSessions sparing the session keepSessionID.
+func (s *APIServer) revokeOtherSessions(ctx context.Context, userID, keepSessionID uuid.UUID) error {
+	if err := s.denylist.RevokeOtherSessions(ctx, userID, keepSessionID); err != nil {
+		return err
+	}
+	_, err := s.store.Sessions.DeleteOtherSessions(ctx, userID, keepSessionID)
+	return err
+}
@@ -0,0 +1,70 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	SignupOpen   = "open"
+	SignupDomain = "domain"
+	SignupInvite = "invite"
+)
+
+// SignupPolicy decides who may sign up: anyone, only emails in one of
+// AllowedDomains, or only holders of an invite code.
+type SignupPolicy struct {
+	Mode           string
+	AllowedDomains []string
+}
+
+func NewSignupPolicy(mode string, allowedDomains []string) (SignupPolicy, error) {
+	policy := SignupPolicy{Mode: mode}
+	for _, domain := range allowedDomains {
+		if strings.TrimSpace(domain) == "" {
+			continue
+		}
+		normalized, err := normalizeDomain(domain)
+		if err != nil {
+			return policy, fmt.Errorf("invalid allowed domain %q: %w", domain, err)
+		}
+		policy.AllowedDomains = append(policy.AllowedDomains, normalized)
+	}
+
+	switch mode {
+	case SignupOpen, SignupInvite:
+	case SignupDomain:
+		if len(policy.AllowedDomains) == 0 {
+			return policy, errors.New("the domain signup policy needs allowed domains")
+		}
+	default:
+		return policy, fmt.Errorf("unknown signup policy %q", mode)
+	}
+	return policy, nil
+}
+
+// AllowsEmail reports whether email may sign up. Under the domain policy its
+// domain must be one of the allowed domains, subdomains are not allowed.
+func (p SignupPolicy) AllowsEmail(email string) bool {
+	if p.Mode != SignupDomain {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	return slices.Contains(p.AllowedDomains, strings.ToLower(email[at+1:]))
+}
+
+func (p SignupPolicy) RequiresInvite() bool {
+	return p.Mode == SignupInvite
+}
+
+// RequiresVerifiedEmail reports whether users must verify their email before
+// using the API. The domain and invite policies restrict who may sign up, so
+// an address that was never proven to be the user's must not grant access.
+func (p SignupPolicy) RequiresVerifiedEmail() bool {
+	return p.Mode != SignupOpen
+}
@@ -0,0 +1,63 @@
+package apiserver_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/apiserver"
+)
+
+var _ = Describe("SignupPolicy", func() {
+	It("should reject unknown policies", func() {
+		_, err := apiserver.NewSignupPolicy("closed", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should require allowed domains for the domain policy", func() {
+		_, err := apiserver.NewSignupPolicy(apiserver.SignupDomain, []string{" "})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should allow any email for the open and invite policies", func() {
+		for _, mode := range []string{apiserver.SignupOpen, apiserver.SignupInvite} {
+			policy, err := apiserver.NewSignupPolicy(mode, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.AllowsEmail("someone@anywhere.com")).To(BeTrue())
+			Expect(policy.RequiresInvite()).To(Equal(mode == apiserver.SignupInvite))
+		}
+	})
+
+	It("should require verified emails unless signup is open", func() {
+		for _, mode := range []string{apiserver.SignupOpen, apiserver.SignupDomain, apiserver.SignupInvite} {
+			policy, err := apiserver.NewSignupPolicy(mode, []string{"example.com"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.RequiresVerifiedEmail()).To(Equal(mode != apiserver.SignupOpen))
+		}
+	})
+
+	It("should reject invalid allowed domains", func() {
+		_, err := apiserver.NewSignupPolicy(apiserver.SignupDomain, []string{"exa mple.com"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should match internationalized allowed domains in punycode", func() {
+		policy, err := apiserver.NewSignupPolicy(apiserver.SignupDomain, []string{"Bücher.example"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(policy.AllowsEmail("bob@xn--bcher-kva.example")).To(BeTrue())
+	})
+
+	DescribeTable("should only allow emails in allowed domains",
+		func(email string, allowed bool) {
+			policy, err := apiserver.NewSignupPolicy(apiserver.SignupDomain, []string{"Example.com", " example.org"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy.AllowsEmail(email)).To(Equal(allowed))
+		},
+		Entry("allowed domain", "bob@example.com", true),
+		Entry("second allowed domain", "bob@example.org", true),
+		Entry("different case", "Bob@EXAMPLE.COM", true),
+		Entry("other domain", "bob@example.net", false),
+		Entry("subdomain", "bob@mail.example.com", false),
+		Entry("suffix", "bob@notexample.com", false),
+		Entry("domain in local part", "example.com@evil.com", false),
+		Entry("no domain", "bob", false),
+	)
+})
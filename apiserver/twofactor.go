@@ -0,0 +1,276 @@
+package apiserver
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/store"
+	"github.com/talvor/asyncapi/totp"
+)
+
+const recoveryCodeCount = 10
+
+// totpSkew is the number of time steps a code may be early or late.
+const totpSkew = 1
+
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+func (s *APIServer) setupTwoFactorHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		user := currentUser(c)
+		if user.IsTOTPEnabled() {
+			return NewErrWithStatus(fiber.StatusConflict, errors.New("two-factor authentication already enabled"))
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if _, err := s.store.Users.SetPendingTOTPSecret(c.UserContext(), user.ID, secret); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		if err := encode(APIResponse[TwoFactorSetupResponse]{
+			Data: &TwoFactorSetupResponse{
+				Secret: secret,
+				URI:    totp.URI(s.config.TOTPIssuer, user.Email, secret),
+			},
+		}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code"`
+}
+
+func (r ConfirmTwoFactorRequest) Validate() error {
+	if r.Code == "" {
+		return errors.New("code is required")
+	}
+	return nil
+}
+
+type ConfirmTwoFactorResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (s *APIServer) confirmTwoFactorHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[ConfirmTwoFactorRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		user := currentUser(c)
+		if user.IsTOTPEnabled() {
+			return NewErrWithStatus(fiber.StatusConflict, errors.New("two-factor authentication already enabled"))
+		}
+		if user.TOTPSecret == nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, errors.New("two-factor authentication setup not started"))
+		}
+
+		step, ok, err := totp.Validate(*user.TOTPSecret, req.Code, time.Now(), totpSkew)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if !ok {
+			return NewErrWithStatus(fiber.StatusBadRequest, errors.New("invalid code"))
+		}
+
+		codes := make([]string, recoveryCodeCount)
+		for i := range codes {
+			if codes[i], err = dto.NewRecoveryCode(); err != nil {
+				return NewErrWithStatus(fiber.StatusInternalServerError, err)
+			}
+		}
+		if err := s.store.RecoveryCodes.Replace(c.UserContext(), user.ID, codes); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if _, err := s.store.Users.EnableTOTP(c.UserContext(), user.ID, step); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		// Sessions signed in before enrollment have no second factor, and
+		// may belong to whoever prompted it.
+		sessionID, _ := c.Locals("session_id").(uuid.UUID)
+		if err := s.revokeOtherSessions(c.UserContext(), user.ID, sessionID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, userEvent(dto.AuditTwoFactorEnable, user.ID))
+
+		// The codes are only stored hashed, this is the only time they are shown.
+		if err := encode(APIResponse[ConfirmTwoFactorResponse]{
+			Data: &ConfirmTwoFactorResponse{RecoveryCodes: codes},
+		}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+}
+
+func (r DisableTwoFactorRequest) Validate() error {
+	if r.Password == "" {
+		return errors.New("password is required")
+	}
+	return nil
+}
+
+func (s *APIServer) disableTwoFactorHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[DisableTwoFactorRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		user := currentUser(c)
+		if err := s.verifyPassword(c, user, req.Password); err != nil {
+			return err
+		}
+
+		if _, err := s.store.Users.DisableTOTP(c.UserContext(), user.ID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if _, err := s.store.RecoveryCodes.DeleteUserCodes(c.UserContext(), user.ID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, userEvent(dto.AuditTwoFactorDisable, user.ID))
+
+		if err := encode(APIResponse[struct{}]{Message: "successfully disabled two-factor authentication"}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// SigninTwoFactorRequest completes a signin with either a TOTP code or a
+// recovery code.
+type SigninTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+func (r SigninTwoFactorRequest) Validate() error {
+	if r.ChallengeToken == "" {
+		return errors.New("challenge_token is required")
+	}
+	if (r.Code == "") == (r.RecoveryCode == "") {
+		return errors.New("either code or recovery_code is required")
+	}
+	return nil
+}
+
+func (s *APIServer) signinTwoFactorHandler() fiber.Handler {
+	return handler(func(c *fiber.Ctx) error {
+		req, err := decode[SigninTwoFactorRequest](c)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusBadRequest, err)
+		}
+
+		challengeToken, err := s.jwtManager.Parse(req.ChallengeToken)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusUnauthorized, err)
+		}
+		if !s.jwtManager.IsChallengeToken(challengeToken) {
+			return NewErrWithStatus(fiber.StatusUnauthorized, errors.New("not a challenge token"))
+		}
+
+		userID, err := s.jwtManager.GetUserIDFromToken(challengeToken)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusUnauthorized, err)
+		}
+		user, err := s.store.Users.ByID(c.UserContext(), userID)
+		if err != nil {
+			status := fiber.StatusInternalServerError
+			if errors.Is(err, sql.ErrNoRows) {
+				status = fiber.StatusUnauthorized
+			}
+			return NewErrWithStatus(status, err)
+		}
+
+		now := time.Now()
+		if user.IsDisabled() {
+			s.audit(c, failedUserEvent(dto.AuditSigninTwoFactor, user.ID, "disabled"))
+			return NewErrWithStatus(fiber.StatusUnauthorized, fmt.Errorf("user %s is disabled: %w", user.ID, errInvalidCredentials))
+		}
+		if !user.IsTOTPEnabled() {
+			return NewErrWithStatus(fiber.StatusUnauthorized, fmt.Errorf("user %s has no two-factor authentication", user.ID))
+		}
+
+		// Wrong codes count towards the signin lockout like wrong passwords,
+		// and the attempt is counted before the code is checked.
+		if _, err := s.store.Users.ClaimSigninAttempt(c.UserContext(), user.ID, s.signinThrottle, now); err != nil {
+			if errors.Is(err, store.ErrUserLocked) {
+				s.audit(c, failedUserEvent(dto.AuditSigninTwoFactor, user.ID, "locked"))
+				return NewErrWithStatus(fiber.StatusUnauthorized, fmt.Errorf("%w: %w", err, errInvalidCredentials))
+			}
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		verified, err := s.verifySecondFactor(c, user, req, now)
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		if !verified {
+			s.audit(c, failedUserEvent(dto.AuditSigninTwoFactor, user.ID, "invalid_code"))
+			return NewErrWithStatus(fiber.StatusUnauthorized, errors.New("invalid two-factor code"))
+		}
+
+		if _, err := s.store.Users.ResetFailedSignins(c.UserContext(), user.ID); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+
+		tokenPair, err := s.startSession(c, user, []string{AMRPassword, AMRMultiFactor})
+		if err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		s.audit(c, userEvent(dto.AuditSigninTwoFactor, user.ID))
+
+		if err := encode(APIResponse[SigninResponse]{
+			Data: &SigninResponse{
+				AccessToken:  tokenPair.AccessToken.Raw,
+				RefreshToken: tokenPair.RefreshToken.Raw,
+			},
+		}, fiber.StatusOK, c); err != nil {
+			return NewErrWithStatus(fiber.StatusInternalServerError, err)
+		}
+		return nil
+	})
+}
+
+// verifySecondFactor checks the TOTP code or consumes the recovery code of
+// req. A TOTP code is accepted once.
+func (s *APIServer) verifySecondFactor(c *fiber.Ctx, user *dto.User, req SigninTwoFactorRequest, now time.Time) (bool, error) {
+	if req.RecoveryCode != "" {
+		_, err := s.store.RecoveryCodes.Consume(c.UserContext(), user.ID, req.RecoveryCode)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	step, ok, err := totp.Validate(*user.TOTPSecret, req.Code, now, totpSkew)
+	if err != nil || !ok {
+		return false, err
+	}
+	_, err = s.store.Users.UseTOTPStep(c.UserContext(), user.ID, step)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
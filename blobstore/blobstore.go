@@ -0,0 +1,69 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/talvor/asyncapi/config"
+)
+
+// ErrNotConfigured is returned by New when no bucket is configured.
+var ErrNotConfigured = errors.New("blob storage is not configured")
+
+// BlobStore holds report outputs, keyed by their output file path.
+type BlobStore interface {
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the S3 blob store of conf.S3Bucket, failing with
+// ErrNotConfigured when the bucket is not set.
+func New(ctx context.Context, conf *config.Config) (BlobStore, error) {
+	if conf.S3Bucket == "" {
+		return nil, ErrNotConfigured
+	}
+
+	sdkConfig, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	client := s3.NewFromConfig(sdkConfig, func(o *s3.Options) {
+		if conf.S3Endpoint != "" {
+			o.BaseEndpoint = aws.String(conf.S3Endpoint)
+			o.UsePathStyle = true
+		}
+	})
+	return &S3BlobStore{client: client, bucket: conf.S3Bucket}, nil
+}
+
+type S3BlobStore struct {
+	client *s3.Client
+	bucket string
+}
+
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s from bucket %s: %w", key, s.bucket, err)
+	}
+	return out.Body, nil
+}
+
+// Delete deletes key, succeeding when it does not exist.
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}); err != nil {
+		return fmt.Errorf("failed to delete %s from bucket %s: %w", key, s.bucket, err)
+	}
+	return nil
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/talvor/asyncapi/apiserver"
 	"github.com/talvor/asyncapi/config"
+	"github.com/talvor/asyncapi/logging"
+	"github.com/talvor/asyncapi/metrics"
 	"github.com/talvor/asyncapi/store"
+	"github.com/talvor/asyncapi/tracing"
 )
 
 func main() {
@@ -17,13 +21,30 @@ func main() {
 func run() error {
 	conf := config.GetConfig()
 
+	if err := logging.Setup(conf); err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), conf, "asyncapi-apiserver")
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := store.NewPostgresDB(conf)
 	if err != nil {
 		return err
 	}
 
+	if err = metrics.RegisterDB(db, conf.DatabaseName); err != nil {
+		return err
+	}
+
 	dataStore := store.New(db)
-	srv := apiserver.New(conf, dataStore)
+	srv, err := apiserver.New(conf, dataStore)
+	if err != nil {
+		return err
+	}
 
 	err = srv.Start()
 	if err != nil {
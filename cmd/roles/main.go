@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/talvor/asyncapi/config"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/store"
+)
+
+// Assigns a role to, or removes it from, the user with the given email. The
+// user has to sign in again, or refresh their tokens, for it to take effect.
+func main() {
+	email := flag.String("email", "", "email of the user")
+	role := flag.String("role", dto.RoleAdmin, "role to assign")
+	remove := flag.Bool("remove", false, "remove the role instead")
+	flag.Parse()
+
+	if err := run(*email, *role, *remove); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(email, role string, remove bool) error {
+	if email == "" {
+		return errors.New("-email is required")
+	}
+
+	db, err := store.NewPostgresDB(config.GetConfig())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	dataStore := store.New(db)
+
+	user, err := dataStore.Users.ByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	eventType := dto.AuditAdminGrantRole
+	if remove {
+		eventType = dto.AuditAdminRevokeRole
+		if _, err := dataStore.Roles.Unassign(ctx, user.ID, role); err != nil {
+			return err
+		}
+	} else if _, err := dataStore.Roles.Assign(ctx, user.ID, role); err != nil {
+		return err
+	}
+
+	// There is no request, the user agent tells the event came from here.
+	if _, err := dataStore.AuditEvents.Record(ctx, &dto.AuditEvent{
+		EventType:  eventType,
+		Outcome:    dto.AuditOutcomeSuccess,
+		Reason:     role,
+		TargetType: dto.AuditTargetUser,
+		TargetID:   user.ID.String(),
+		UserAgent:  "cmd/roles",
+	}); err != nil {
+		return err
+	}
+
+	roles, err := dataStore.Roles.RolesByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s roles: %v\n", user.Email, roles)
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/mcuadros/go-defaults"
 	"github.com/spf13/viper"
@@ -27,6 +28,79 @@ type Config struct {
 	APIHost          string `mapstructure:"API_HOST"`
 	JwtSecret        string `mapstructure:"JWT_SECRET"`
 
+	// Client IPs are read from ProxyHeader only on requests from one of
+	// TrustedProxies (IPs or CIDR ranges)
+	ProxyHeader    string   `mapstructure:"PROXY_HEADER"`
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// Asymmetric JWT signing, JwtSecret is used when no signing key is set
+	JwtSigningKeyFile       string   `mapstructure:"JWT_SIGNING_KEY_FILE"`
+	JwtVerificationKeyFiles []string `mapstructure:"JWT_VERIFICATION_KEY_FILES"`
+	// How long an access token found not revoked is trusted without checking
+	// the denylist again
+	JwtDenylistCacheTTL time.Duration `mapstructure:"JWT_DENYLIST_CACHE_TTL" default:"5s"`
+
+	// Token policy, the issuer defaults to http://<API_HOST>:<API_PORT> and
+	// the audience is neither set nor checked when empty
+	JwtIssuer          string        `mapstructure:"JWT_ISSUER"`
+	JwtAudience        string        `mapstructure:"JWT_AUDIENCE"`
+	JwtAccessTokenTTL  time.Duration `mapstructure:"JWT_ACCESS_TOKEN_TTL" default:"15m"`
+	JwtRefreshTokenTTL time.Duration `mapstructure:"JWT_REFRESH_TOKEN_TTL" default:"720h"`
+	JwtLeeway          time.Duration `mapstructure:"JWT_LEEWAY" default:"30s"`
+	// Time after signin past which a session can no longer be refreshed, no
+	// limit when zero
+	SessionMaxLifetime time.Duration `mapstructure:"SESSION_MAX_LIFETIME" default:"0s"`
+
+	// Password hashing with argon2id, memory is in KiB. Changing the pepper
+	// invalidates every argon2id password hash.
+	PasswordArgon2Memory      uint32 `mapstructure:"PASSWORD_ARGON2_MEMORY" default:"65536"`
+	PasswordArgon2Iterations  uint32 `mapstructure:"PASSWORD_ARGON2_ITERATIONS" default:"3"`
+	PasswordArgon2Parallelism uint8  `mapstructure:"PASSWORD_ARGON2_PARALLELISM" default:"2"`
+	PasswordPepper            string `mapstructure:"PASSWORD_PEPPER"`
+
+	// Two-factor authentication
+	TOTPIssuer      string        `mapstructure:"TOTP_ISSUER" default:"asyncapi"`
+	MFAChallengeTTL time.Duration `mapstructure:"MFA_CHALLENGE_TTL" default:"5m"`
+
+	// Signup policy, open, domain (SignupAllowedDomains only) or invite (an
+	// invite code is required)
+	SignupPolicy         string   `mapstructure:"SIGNUP_POLICY" default:"open"`
+	SignupAllowedDomains []string `mapstructure:"SIGNUP_ALLOWED_DOMAINS"`
+
+	// Signin lockout
+	SigninLockoutThreshold int           `mapstructure:"SIGNIN_LOCKOUT_THRESHOLD" default:"5"`
+	SigninLockoutDuration  time.Duration `mapstructure:"SIGNIN_LOCKOUT_DURATION" default:"15m"`
+	SigninBaseDelay        time.Duration `mapstructure:"SIGNIN_BASE_DELAY" default:"1s"`
+
+	// Rate limiting, limits are written as <requests>/<period>
+	RateLimitStore string `mapstructure:"RATE_LIMIT_STORE" default:"memory"`
+	RateLimitAuth  string `mapstructure:"RATE_LIMIT_AUTH" default:"10/1m"`
+	RateLimitAPI   string `mapstructure:"RATE_LIMIT_API" default:"120/1m"`
+
+	// Email, the mailer is log, file (written to MailerDir) or smtp. It must
+	// be set outside local development, where it defaults to file.
+	Mailer               string        `mapstructure:"MAILER"`
+	MailerDir            string        `mapstructure:"MAILER_DIR" default:"mail"`
+	MailFrom             string        `mapstructure:"MAIL_FROM" default:"no-reply@localhost"`
+	SMTPHost             string        `mapstructure:"SMTP_HOST"`
+	SMTPPort             string        `mapstructure:"SMTP_PORT" default:"587"`
+	SMTPUsername         string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword         string        `mapstructure:"SMTP_PASSWORD"`
+	EmailVerificationTTL time.Duration `mapstructure:"EMAIL_VERIFICATION_TTL" default:"24h"`
+	PasswordResetTTL     time.Duration `mapstructure:"PASSWORD_RESET_TTL" default:"1h"`
+	InvitationTTL        time.Duration `mapstructure:"INVITATION_TTL" default:"168h"`
+
+	// Time during which a deleted report can be restored before it is purged
+	ReportRestorePeriod time.Duration `mapstructure:"REPORT_RESTORE_PERIOD" default:"720h"`
+
+	// Observability, metrics are served on their own listener so that they
+	// can be kept off the public network
+	LogLevel       string `mapstructure:"LOG_LEVEL" default:"info"`
+	LogFormat      string `mapstructure:"LOG_FORMAT" default:"json"`
+	TracesExporter string `mapstructure:"OTEL_TRACES_EXPORTER" default:"none"`
+	MetricsHost    string `mapstructure:"METRICS_HOST" default:"localhost"`
+	MetricsPort    string `mapstructure:"METRICS_PORT" default:"9090"`
+
 	// AWS
 	S3Endpoint  string `mapstructure:"S3_LOCALSTACK_ENDPOINT"`
 	SQSEndpoint string `mapstructure:"LOCALSTACK_ENDPOINT"`
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Scopes an API key can be granted. Requests authenticated with a session
+// are not limited by scopes.
+const (
+	ScopeReportsRead  = "reports:read"
+	ScopeReportsWrite = "reports:write"
+)
+
+var Scopes = []string{ScopeReportsRead, ScopeReportsWrite}
+
+const apiKeyPrefix = "ak_"
+
+// APIKey lets machine clients act as the user owning it, within its scopes.
+type APIKey struct {
+	ID           uuid.UUID      `db:"id"`
+	UserID       uuid.UUID      `db:"user_id"`
+	Name         string         `db:"name"`
+	Prefix       string         `db:"prefix"`
+	HashedSecret string         `db:"hashed_secret"`
+	Scopes       pq.StringArray `db:"scopes"`
+	CreatedAt    time.Time      `db:"created_at"`
+	ExpiresAt    *time.Time     `db:"expires_at"`
+	LastUsedAt   *time.Time     `db:"last_used_at"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
+
+// NewAPIKey returns a random key prefix and secret.
+func NewAPIKey() (prefix, secret string, err error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key prefix: %w", err)
+	}
+	prefix = strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+
+	secret, err = NewSecretToken()
+	if err != nil {
+		return "", "", err
+	}
+	return prefix, secret, nil
+}
+
+// FormatAPIKey returns the key handed to the client.
+func FormatAPIKey(prefix, secret string) string {
+	return apiKeyPrefix + prefix + "_" + secret
+}
+
+// ParseAPIKey splits a key returned by FormatAPIKey into its prefix and
+// secret.
+func ParseAPIKey(key string) (prefix, secret string, ok bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return "", "", false
+	}
+	prefix, secret, ok = strings.Cut(rest, "_")
+	return prefix, secret, ok && prefix != "" && secret != ""
+}
@@ -0,0 +1,30 @@
+package dto_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+)
+
+var _ = Describe("API keys", func() {
+	It("should parse formatted keys", func() {
+		prefix, secret, err := dto.NewAPIKey()
+		Expect(err).NotTo(HaveOccurred())
+
+		gotPrefix, gotSecret, ok := dto.ParseAPIKey(dto.FormatAPIKey(prefix, secret))
+		Expect(ok).To(BeTrue())
+		Expect(gotPrefix).To(Equal(prefix))
+		Expect(gotSecret).To(Equal(secret))
+	})
+
+	DescribeTable("should reject malformed keys",
+		func(key string) {
+			_, _, ok := dto.ParseAPIKey(key)
+			Expect(ok).To(BeFalse())
+		},
+		Entry("empty", ""),
+		Entry("no prefix", "abc_def"),
+		Entry("no secret", "ak_abc_"),
+		Entry("no separator", "ak_abc"),
+	)
+})
@@ -0,0 +1,74 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit event types.
+const (
+	AuditSignup             = "auth.signup"
+	AuditSignin             = "auth.signin"
+	AuditSigninTwoFactor    = "auth.signin_2fa"
+	AuditRefresh            = "auth.refresh"
+	AuditRefreshTokenReuse  = "auth.refresh_token_reuse"
+	AuditSignout            = "auth.signout"
+	AuditSignoutAll         = "auth.signout_all"
+	AuditSessionRevoke      = "auth.session_revoke"
+	AuditPasswordChange     = "auth.password_change"
+	AuditPasswordReset      = "auth.password_reset"
+	AuditTwoFactorEnable    = "auth.2fa_enable"
+	AuditTwoFactorDisable   = "auth.2fa_disable"
+	AuditAPIKeyCreate       = "auth.api_key_create"
+	AuditAPIKeyDelete       = "auth.api_key_delete"
+	AuditAccountDelete      = "account.delete"
+	AuditAccountExport      = "account.export"
+	AuditOrgMemberUpdate    = "org.member_update"
+	AuditOrgMemberRemove    = "org.member_remove"
+	AuditAdminDisableUser   = "admin.disable_user"
+	AuditAdminEnableUser    = "admin.enable_user"
+	AuditAdminUnlockUser    = "admin.unlock_user"
+	AuditAdminSignoutUser   = "admin.signout_user"
+	AuditAdminRequeueReport = "admin.requeue_report"
+	AuditAdminFailReport    = "admin.fail_report"
+	AuditAdminCreateInvite  = "admin.create_invite_code"
+	AuditAdminRevokeInvite  = "admin.revoke_invite_code"
+	AuditAdminGrantRole     = "admin.grant_role"
+	AuditAdminRevokeRole    = "admin.revoke_role"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// Types of the targets of audit events. Memberships are identified as
+// <org id>/<user id>.
+const (
+	AuditTargetUser       = "user"
+	AuditTargetSession    = "session"
+	AuditTargetReport     = "report"
+	AuditTargetInvite     = "invite_code"
+	AuditTargetEmail      = "email"
+	AuditTargetAPIKey     = "api_key"
+	AuditTargetMembership = "membership"
+)
+
+// AuditEvent is an entry of the security audit log. Fields that do not apply
+// to an event are left empty; ActorID is nil when the actor is unknown, such
+// as for a signin with an unknown email. Reason explains failures, and names
+// the role given by role and membership changes.
+type AuditEvent struct {
+	ID         int64      `db:"id"`
+	CreatedAt  time.Time  `db:"created_at"`
+	EventType  string     `db:"event_type"`
+	Outcome    string     `db:"outcome"`
+	Reason     string     `db:"reason"`
+	ActorID    *uuid.UUID `db:"actor_id"`
+	TargetType string     `db:"target_type"`
+	TargetID   string     `db:"target_id"`
+	IP         string     `db:"ip"`
+	UserAgent  string     `db:"user_agent"`
+	RequestID  string     `db:"request_id"`
+}
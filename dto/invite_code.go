@@ -0,0 +1,17 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InviteCode struct {
+	ID         uuid.UUID  `db:"id"`
+	HashedCode string     `db:"hashed_code"`
+	MaxUses    int        `db:"max_uses"`
+	Uses       int        `db:"uses"`
+	CreatedBy  *uuid.UUID `db:"created_by"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+}
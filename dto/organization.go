@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Roles of a user within an organization. Admins manage its members and
+// invitations.
+const (
+	OrgRoleMember = "member"
+	OrgRoleAdmin  = "admin"
+)
+
+var OrgRoles = []string{OrgRoleMember, OrgRoleAdmin}
+
+type Organization struct {
+	ID        uuid.UUID `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Membership struct {
+	OrgID     uuid.UUID `db:"org_id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Role      string    `db:"role"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (m *Membership) IsAdmin() bool {
+	return m.Role == OrgRoleAdmin
+}
+
+// UserOrganization is an organization as seen by one of its members.
+type UserOrganization struct {
+	Organization
+	Role string `db:"role"`
+}
+
+// Member is a membership together with the member's profile.
+type Member struct {
+	Membership
+	Email string `db:"email"`
+	Name  string `db:"name"`
+}
+
+type Invitation struct {
+	HashedToken string     `db:"hashed_token"`
+	OrgID       uuid.UUID  `db:"org_id"`
+	Email       string     `db:"email"`
+	Role        string     `db:"role"`
+	InvitedBy   *uuid.UUID `db:"invited_by"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+}
@@ -0,0 +1,173 @@
+package dto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Passwords are hashed with argon2id and stored in the PHC string format:
+//
+//	$argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+//
+// Hashes created before argon2id are base64 encoded bcrypt hashes. They are
+// still verified, and PasswordNeedsRehash reports them so that they can be
+// replaced on the next successful signin.
+
+const argon2idPrefix = "$argon2id$"
+
+var ErrPasswordMismatch = errors.New("password does not match hash")
+
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var passwordHashing = struct {
+	sync.RWMutex
+	params Argon2Params
+	pepper []byte
+}{params: DefaultArgon2Params}
+
+// SetPasswordHashing sets the argon2id parameters new hashes are created with
+// and the server-side pepper mixed into every argon2id hash. Hashes with other
+// parameters still verify but need a rehash. Changing the pepper invalidates
+// every argon2id hash, so it must be set before the first one is created.
+func SetPasswordHashing(params Argon2Params, pepper string) {
+	passwordHashing.Lock()
+	defer passwordHashing.Unlock()
+	passwordHashing.params = params
+	passwordHashing.pepper = []byte(pepper)
+}
+
+func currentPasswordHashing() (Argon2Params, []byte) {
+	passwordHashing.RLock()
+	defer passwordHashing.RUnlock()
+	return passwordHashing.params, passwordHashing.pepper
+}
+
+func HashPassword(password string) (string, error) {
+	params, pepper := currentPasswordHashing()
+
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey(peppered(password, pepper), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		params.Memory, params.Iterations, params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func CheckPasswordHash(password, hash string) error {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return checkBcryptHash(password, hash)
+	}
+
+	params, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return err
+	}
+
+	_, pepper := currentPasswordHashing()
+	other := argon2.IDKey(peppered(password, pepper), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// PasswordNeedsRehash reports whether hash was created with another algorithm
+// or other parameters than new hashes are.
+func PasswordNeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, argon2idPrefix) {
+		return true
+	}
+
+	params, salt, _, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+
+	current, _ := currentPasswordHashing()
+	params.SaltLength = uint32(len(salt))
+	return params != current
+}
+
+func decodeArgon2idHash(hash string) (params Argon2Params, salt, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash version: %w", err)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash parameters: %w", err)
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash key: %w", err)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+// peppered mixes the server-side pepper into password, if one is set.
+func peppered(password string, pepper []byte) []byte {
+	if len(pepper) == 0 {
+		return []byte(password)
+	}
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
+
+// checkBcryptHash verifies a legacy base64 encoded bcrypt hash.
+func checkBcryptHash(password, hash string) error {
+	hashedPassword, err := base64.StdEncoding.DecodeString(hash)
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
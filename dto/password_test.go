@@ -0,0 +1,73 @@
+package dto_test
+
+import (
+	"encoding/base64"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ = Describe("Password hashing", func() {
+	// Cheap parameters keep the tests fast.
+	params := dto.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	BeforeEach(func() {
+		dto.SetPasswordHashing(params, "")
+		DeferCleanup(dto.SetPasswordHashing, dto.DefaultArgon2Params, "")
+	})
+
+	It("should hash passwords with argon2id in PHC format", func() {
+		hash, err := dto.HashPassword("password")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hash).To(HavePrefix("$argon2id$v=19$m=1024,t=1,p=1$"))
+		Expect(len(hash)).To(BeNumerically("<=", 255))
+
+		Expect(dto.CheckPasswordHash("password", hash)).To(Succeed())
+		Expect(dto.CheckPasswordHash("wrong", hash)).To(MatchError(dto.ErrPasswordMismatch))
+		Expect(dto.PasswordNeedsRehash(hash)).To(BeFalse())
+	})
+
+	It("should verify legacy bcrypt hashes and ask for a rehash", func() {
+		bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		Expect(err).NotTo(HaveOccurred())
+		hash := base64.StdEncoding.EncodeToString(bcryptHash)
+
+		Expect(dto.CheckPasswordHash("password", hash)).To(Succeed())
+		Expect(dto.CheckPasswordHash("wrong", hash)).To(MatchError(dto.ErrPasswordMismatch))
+		Expect(dto.PasswordNeedsRehash(hash)).To(BeTrue())
+	})
+
+	It("should ask for a rehash when the parameters change", func() {
+		hash, err := dto.HashPassword("password")
+		Expect(err).NotTo(HaveOccurred())
+
+		stronger := params
+		stronger.Iterations = 2
+		dto.SetPasswordHashing(stronger, "")
+
+		Expect(dto.CheckPasswordHash("password", hash)).To(Succeed())
+		Expect(dto.PasswordNeedsRehash(hash)).To(BeTrue())
+	})
+
+	It("should mix in the pepper", func() {
+		dto.SetPasswordHashing(params, "pepper")
+		hash, err := dto.HashPassword("password")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dto.CheckPasswordHash("password", hash)).To(Succeed())
+
+		dto.SetPasswordHashing(params, "other")
+		Expect(dto.CheckPasswordHash("password", hash)).To(MatchError(dto.ErrPasswordMismatch))
+	})
+
+	It("should reject malformed hashes", func() {
+		hash, err := dto.HashPassword("password")
+		Expect(err).NotTo(HaveOccurred())
+
+		truncated := hash[:strings.LastIndex(hash, "$")]
+		Expect(dto.CheckPasswordHash("password", truncated)).To(MatchError(ContainSubstring("malformed")))
+		Expect(dto.PasswordNeedsRehash(truncated)).To(BeTrue())
+	})
+})
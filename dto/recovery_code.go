@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the user
+// has lost their authenticator.
+type RecoveryCode struct {
+	UserID     uuid.UUID `db:"user_id"`
+	HashedCode string    `db:"hashed_code"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// NewRecoveryCode returns a random code formatted as xxxxx-xxxxx.
+func NewRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	for i := range b {
+		b[i] = recoveryCodeAlphabet[int(b[i])%len(recoveryCodeAlphabet)]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+// HashRecoveryCode hashes code for storage, ignoring case and separators.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashSecretToken(normalized)
+}
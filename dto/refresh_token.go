@@ -10,10 +10,12 @@ import (
 )
 
 type RefreshToken struct {
-	UserID      uuid.UUID `db:"user_id"`
-	HashedToken string    `db:"hashed_token"`
-	CreatedAt   time.Time `db:"created_at"`
-	ExpiresAt   time.Time `db:"expires_at"`
+	UserID      uuid.UUID  `db:"user_id"`
+	SessionID   uuid.UUID  `db:"session_id"`
+	HashedToken string     `db:"hashed_token"`
+	CreatedAt   time.Time  `db:"created_at"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	RotatedAt   *time.Time `db:"rotated_at"`
 }
 
 func HashToken(token *jwt.Token) (string, error) {
@@ -9,6 +9,7 @@ import (
 type Report struct {
 	UserID               uuid.UUID  `db:"user_id"`
 	ID                   uuid.UUID  `db:"id"`
+	OrgID                *uuid.UUID `db:"org_id"`
 	ReportType           string     `db:"report_type"`
 	OutputFilePath       *string    `db:"output_file_path"`
 	DownloadURL          *string    `db:"download_url"`
@@ -18,4 +19,29 @@ type Report struct {
 	StartedAt            *time.Time `db:"started_at"`
 	CompletedAt          *time.Time `db:"completed_at"`
 	FailedAt             *time.Time `db:"failed_at"`
+	TraceParent          *string    `db:"trace_parent"`
+	DeletedAt            *time.Time `db:"deleted_at"`
+}
+
+// Report statuses, derived from the report's timestamps.
+const (
+	ReportStatusPending   = "pending"
+	ReportStatusRunning   = "running"
+	ReportStatusCompleted = "completed"
+	ReportStatusFailed    = "failed"
+)
+
+var ReportStatuses = []string{ReportStatusPending, ReportStatusRunning, ReportStatusCompleted, ReportStatusFailed}
+
+func (r *Report) Status() string {
+	switch {
+	case r.FailedAt != nil:
+		return ReportStatusFailed
+	case r.CompletedAt != nil:
+		return ReportStatusCompleted
+	case r.StartedAt != nil:
+		return ReportStatusRunning
+	default:
+		return ReportStatusPending
+	}
 }
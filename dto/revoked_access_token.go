@@ -0,0 +1,12 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RevokedAccessToken struct {
+	JTI       uuid.UUID `db:"jti"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
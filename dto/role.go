@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const RoleAdmin = "admin"
+
+// Permissions granted through roles, see the role_permissions table.
+const (
+	PermissionUsersRead       = "users:read"
+	PermissionUsersWrite      = "users:write"
+	PermissionReportsReadAll  = "reports:read_all"
+	PermissionReportsWriteAll = "reports:write_all"
+	PermissionAuditRead       = "audit:read"
+	PermissionInvitesRead     = "invites:read"
+	PermissionInvitesWrite    = "invites:write"
+)
+
+type Role struct {
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+type UserRole struct {
+	UserID    uuid.UUID `db:"user_id"`
+	Role      string    `db:"role"`
+	CreatedAt time.Time `db:"created_at"`
+}
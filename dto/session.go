@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	ID                   uuid.UUID  `db:"id"`
+	UserID               uuid.UUID  `db:"user_id"`
+	UserAgent            string     `db:"user_agent"`
+	IPAddress            string     `db:"ip_address"`
+	CreatedAt            time.Time  `db:"created_at"`
+	LastUsedAt           time.Time  `db:"last_used_at"`
+	AccessTokenID        *uuid.UUID `db:"access_token_id"`
+	AccessTokenExpiresAt *time.Time `db:"access_token_expires_at"`
+}
@@ -1,45 +1,48 @@
 package dto
 
 import (
-	"encoding/base64"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID                   uuid.UUID `db:"id"`
-	Email                string    `db:"email"`
-	HashedPasswordBase64 string    `db:"hashed_password"`
-	CreatedAt            time.Time `db:"created_at"`
+	ID                   uuid.UUID  `db:"id"`
+	Email                string     `db:"email"`
+	Name                 string     `db:"name"`
+	HashedPassword       string     `db:"hashed_password"`
+	CreatedAt            time.Time  `db:"created_at"`
+	FailedSigninAttempts int        `db:"failed_signin_attempts"`
+	LastFailedSigninAt   *time.Time `db:"last_failed_signin_at"`
+	LockedUntil          *time.Time `db:"locked_until"`
+	EmailVerifiedAt      *time.Time `db:"email_verified_at"`
+	TOTPSecret           *string    `db:"totp_secret"`
+	TOTPEnabledAt        *time.Time `db:"totp_enabled_at"`
+	TOTPLastStep         *int64     `db:"totp_last_step"`
+	DisabledAt           *time.Time `db:"disabled_at"`
 }
 
-func (u *User) ComparePassword(password string) error {
-	err := CheckPasswordHash(password, u.HashedPasswordBase64)
-	if err != nil {
-		return fmt.Errorf("failed to compare password: %w", err)
-	}
-	return nil
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
 }
 
-func CheckPasswordHash(password, hash string) error {
-	hashedPassword, err := base64.StdEncoding.DecodeString(hash)
-	if err != nil {
-		return err
-	}
+func (u *User) IsTOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != nil
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
 
-	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
-	return err
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(now)
 }
 
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (u *User) ComparePassword(password string) error {
+	err := CheckPasswordHash(password, u.HashedPassword)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to compare password: %w", err)
 	}
-
-	hashedPasswordBase64 := base64.StdEncoding.EncodeToString(bytes)
-	return hashedPasswordBase64, nil
+	return nil
 }
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	UserTokenEmailVerification = "email_verification"
+	UserTokenPasswordReset     = "password_reset"
+)
+
+// UserToken is a single-use token emailed to a user for the given purpose.
+type UserToken struct {
+	HashedToken string    `db:"hashed_token"`
+	UserID      uuid.UUID `db:"user_id"`
+	Purpose     string    `db:"purpose"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
+// NewSecretToken returns a random URL-safe token.
+func NewSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashSecretToken hashes a token from NewSecretToken for storage, the same
+// way HashToken does for refresh tokens.
+func HashSecretToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
@@ -74,12 +74,9 @@ func NewTestEnv() (*TestEnv, error) {
 }
 
 func (te *TestEnv) SetupDB() error {
-	m, err := migrate.New(
-		fmt.Sprintf("file:///%s/migrations", te.Config.ProjectRoot),
-		te.Config.DatabaseURL(),
-	)
+	m, err := te.migrate()
 	if err != nil {
-		return fmt.Errorf("failed to migrate db %w", err)
+		return err
 	}
 	if err = m.Up(); err != nil && err != migrate.ErrNoChange {
 		return fmt.Errorf("failed to migrate db %w", err)
@@ -87,8 +84,32 @@ func (te *TestEnv) SetupDB() error {
 	return nil
 }
 
+// MigrateDB migrates the database up or down to version, for tests of data
+// migrations.
+func (te *TestEnv) MigrateDB(version uint) error {
+	m, err := te.migrate()
+	if err != nil {
+		return err
+	}
+	if err = m.Migrate(version); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("failed to migrate db to version %d %w", version, err)
+	}
+	return nil
+}
+
+func (te *TestEnv) migrate() (*migrate.Migrate, error) {
+	m, err := migrate.New(
+		fmt.Sprintf("file:///%s/migrations", te.Config.ProjectRoot),
+		te.Config.DatabaseURL(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate db %w", err)
+	}
+	return m, nil
+}
+
 func (te *TestEnv) TeardownDB() error {
-	_, err := te.DB.Exec(fmt.Sprintf("TRUNCATE TABLE %s;", strings.Join([]string{"users", "refresh_tokens", "reports"}, ",")))
+	_, err := te.DB.Exec(fmt.Sprintf("TRUNCATE TABLE %s;", strings.Join([]string{"users", "user_tokens", "recovery_codes", "user_roles", "sessions", "refresh_tokens", "api_keys", "organizations", "memberships", "invitations", "reports", "rate_limit_buckets", "revoked_access_tokens", "audit_events", "invite_codes", "blob_deletions", "user_email_collisions"}, ",")))
 	if err != nil {
 		return fmt.Errorf("failed to cleanup db %w", err)
 	}
@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/talvor/asyncapi/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attribute keys used to correlate log lines across a request or a job.
+const (
+	KeyRequestID = "request_id"
+	KeyUserID    = "user_id"
+	KeyOrgID     = "org_id"
+	KeyRoute     = "route"
+	KeyReportID  = "report_id"
+	KeyAttempt   = "attempt"
+	KeyTraceID   = "trace_id"
+	KeySpanID    = "span_id"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively against attribute keys; any
+// key containing one of them has its value redacted.
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "cookie", "api_key", "api-key", "apikey"}
+
+// Setup installs the default slog logger using the level and format from conf.
+func Setup(conf *config.Config) error {
+	logger, err := New(os.Stdout, conf.LogLevel, conf.LogFormat)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// New returns a logger writing to w that adds the attributes stored in the
+// context by With to every record and redacts sensitive attributes.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       lvl,
+		ReplaceAttr: redact,
+	}
+
+	var handler slog.Handler
+	switch format {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, opts)
+	case FormatText:
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+
+	return slog.New(contextHandler{handler}), nil
+}
+
+type ctxKey struct{}
+
+// With returns a copy of ctx carrying the given key/value pairs, which are
+// added to every record logged with that context. A key already present in
+// ctx is replaced.
+func With(ctx context.Context, args ...any) context.Context {
+	var r slog.Record
+	r.Add(args...)
+
+	existing := attrsFromContext(ctx)
+	attrs := make([]slog.Attr, 0, len(existing)+r.NumAttrs())
+	attrs = append(attrs, existing...)
+	r.Attrs(func(a slog.Attr) bool {
+		for i := range attrs {
+			if attrs[i].Key == a.Key {
+				attrs[i] = a
+				return true
+			}
+		}
+		attrs = append(attrs, a)
+		return true
+	})
+
+	return context.WithValue(ctx, ctxKey{}, attrs)
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := attrsFromContext(ctx); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String(KeyTraceID, sc.TraceID().String()),
+			slog.String(KeySpanID, sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	if a.Value.Kind() == slog.KindString {
+		v := a.Value.String()
+		if strings.HasPrefix(v, "Bearer ") || strings.HasPrefix(v, "Basic ") || strings.HasPrefix(v, "ApiKey ") {
+			return slog.String(a.Key, redacted)
+		}
+	}
+
+	return a
+}
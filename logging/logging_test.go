@@ -0,0 +1,87 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/logging"
+)
+
+var _ = Describe("Logger", func() {
+	var buf *bytes.Buffer
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+	})
+
+	decode := func() map[string]any {
+		var line map[string]any
+		Expect(json.Unmarshal(buf.Bytes(), &line)).To(Succeed())
+		return line
+	}
+
+	It("should add context attributes to every record", func() {
+		logger, err := logging.New(buf, "info", logging.FormatJSON)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx := logging.With(context.Background(), logging.KeyRequestID, "req-1")
+		ctx = logging.With(ctx, logging.KeyUserID, "user-1")
+		logger.InfoContext(ctx, "hello")
+
+		line := decode()
+		Expect(line[logging.KeyRequestID]).To(Equal("req-1"))
+		Expect(line[logging.KeyUserID]).To(Equal("user-1"))
+	})
+
+	It("should replace a context attribute set twice", func() {
+		logger, err := logging.New(buf, "info", logging.FormatJSON)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx := logging.With(context.Background(), logging.KeyRoute, "/")
+		ctx = logging.With(ctx, logging.KeyRoute, "/auth/signin")
+		logger.InfoContext(ctx, "hello")
+
+		Expect(bytes.Count(buf.Bytes(), []byte(`"route"`))).To(Equal(1))
+		Expect(decode()[logging.KeyRoute]).To(Equal("/auth/signin"))
+	})
+
+	It("should redact sensitive attributes", func() {
+		logger, err := logging.New(buf, "info", logging.FormatJSON)
+		Expect(err).NotTo(HaveOccurred())
+
+		logger.Info("hello",
+			"password", "hunter2",
+			"refresh_token", "abc",
+			"Authorization", "Bearer abc",
+			"header", "Bearer abc",
+			"X-API-Key", "ak_abc",
+			"other_header", "ApiKey ak_abc",
+			"email", "test@testing.com",
+		)
+
+		line := decode()
+		Expect(line["password"]).To(Equal("[REDACTED]"))
+		Expect(line["refresh_token"]).To(Equal("[REDACTED]"))
+		Expect(line["Authorization"]).To(Equal("[REDACTED]"))
+		Expect(line["header"]).To(Equal("[REDACTED]"))
+		Expect(line["X-API-Key"]).To(Equal("[REDACTED]"))
+		Expect(line["other_header"]).To(Equal("[REDACTED]"))
+		Expect(line["email"]).To(Equal("test@testing.com"))
+	})
+
+	It("should not log below the configured level", func() {
+		logger, err := logging.New(buf, "warn", logging.FormatJSON)
+		Expect(err).NotTo(HaveOccurred())
+
+		logger.Info("hello")
+		Expect(buf.Len()).To(BeZero())
+	})
+
+	It("should reject an unknown format", func() {
+		_, err := logging.New(buf, "info", "xml")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware adds the request ID to the request's user context and logs
+// every completed request. It must be installed after the requestid
+// middleware.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		if requestID, ok := c.Locals("requestid").(string); ok {
+			c.SetUserContext(With(c.UserContext(), KeyRequestID, requestID))
+		}
+
+		err := c.Next()
+
+		// Handlers may have added attributes (user, route) to the context.
+		slog.InfoContext(c.UserContext(), "request completed",
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", c.Response().StatusCode(),
+			"duration", time.Since(start),
+			"ip", c.IP(),
+		)
+
+		return err
+	}
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer writes each message to a .eml file in a directory, for local
+// development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%d-%s.eml", now.UnixNano(), strings.NewReplacer("/", "_", "@", "_at_").Replace(msg.To))
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), format(m.from, msg, now), 0o644); err != nil {
+		return fmt.Errorf("failed to write email to %s: %w", msg.To, err)
+	}
+	return nil
+}
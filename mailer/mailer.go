@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/mail"
+	"time"
+
+	"github.com/talvor/asyncapi/config"
+)
+
+const (
+	TransportLog  = "log"
+	TransportFile = "file"
+	TransportSMTP = "smtp"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by conf.Mailer. Outside local development
+// it must be set explicitly; emails carry tokens, so they must not silently
+// go nowhere.
+func New(conf *config.Config) (Mailer, error) {
+	switch conf.Mailer {
+	case "":
+		if conf.Env != config.EnvDev && conf.Env != config.EnvTest {
+			return nil, errors.New("MAILER must be set outside local development")
+		}
+		return NewFileMailer(conf.MailerDir, conf.MailFrom)
+	case TransportLog:
+		return NewLogMailer(conf.MailFrom), nil
+	case TransportFile:
+		return NewFileMailer(conf.MailerDir, conf.MailFrom)
+	case TransportSMTP:
+		return NewSMTPMailer(conf.SMTPHost, conf.SMTPPort, conf.SMTPUsername, conf.SMTPPassword, conf.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", conf.Mailer)
+	}
+}
+
+// format renders msg as an RFC 5322 message sent by from at date.
+func format(from string, msg Message, date time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", (&mail.Address{Address: from}).String())
+	fmt.Fprintf(&b, "To: %s\r\n", (&mail.Address{Address: msg.To}).String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return b.Bytes()
+}
@@ -0,0 +1,68 @@
+package mailer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/config"
+	"github.com/talvor/asyncapi/mailer"
+)
+
+var _ = Describe("FileMailer", func() {
+	It("should write messages to the mail directory", func() {
+		dir := filepath.Join(GinkgoT().TempDir(), "mail")
+		m, err := mailer.NewFileMailer(dir, "no-reply@example.com")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = m.Send(context.Background(), mailer.Message{
+			To:      "user@example.com",
+			Subject: "Verify your email address",
+			Body:    "Your code is 1234",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+
+		content, err := os.ReadFile(files[0])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring("From: <no-reply@example.com>\r\n"))
+		Expect(string(content)).To(ContainSubstring("To: <user@example.com>\r\n"))
+		Expect(string(content)).To(ContainSubstring("Subject: Verify your email address\r\n"))
+		Expect(string(content)).To(HaveSuffix("\r\n\r\nYour code is 1234"))
+	})
+})
+
+var _ = Describe("New", func() {
+	It("should reject an unknown mailer", func() {
+		conf := *config.GetConfig()
+		conf.Mailer = "pigeon"
+
+		_, err := mailer.New(&conf)
+		Expect(err).To(MatchError(ContainSubstring("unknown mailer")))
+	})
+
+	It("should require a mailer outside local development", func() {
+		conf := *config.GetConfig()
+		conf.Mailer = ""
+		conf.Env = "production"
+
+		_, err := mailer.New(&conf)
+		Expect(err).To(MatchError(ContainSubstring("MAILER must be set")))
+	})
+
+	It("should default to the file mailer in local development", func() {
+		conf := *config.GetConfig()
+		conf.Mailer = ""
+		conf.Env = config.EnvDev
+		conf.MailerDir = filepath.Join(GinkgoT().TempDir(), "mail")
+
+		m, err := mailer.New(&conf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(BeAssignableToTypeOf(&mailer.FileMailer{}))
+	})
+})
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends messages through an SMTP server, authenticating with
+// PLAIN auth when a username is set. The server must support STARTTLS for
+// credentials to be sent.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, format(m.from, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "asyncapi"
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests handled, by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency, by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	storeQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "store",
+		Name:      "query_duration_seconds",
+		Help:      "Store method latency, by method and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "outcome"})
+)
+
+// Handler serves the default registry in the Prometheus exposition format.
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.Handler())
+}
+
+// Middleware records request count and latency for every request handled by
+// the app. The route label is the matched route pattern (e.g. /reports/:id)
+// rather than the raw path to keep cardinality bounded.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		labels := prometheus.Labels{
+			"route":  c.Route().Path,
+			"method": c.Method(),
+			"status": strconv.Itoa(status),
+		}
+		httpRequestsTotal.With(labels).Inc()
+		httpRequestDuration.With(labels).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveQuery records the latency of a store method that started at start.
+// err points to the method's error result and determines the outcome label.
+func ObserveQuery(method string, start time.Time, err *error) {
+	outcome := "ok"
+	if err != nil && *err != nil {
+		outcome = "error"
+	}
+	storeQueryDuration.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1 @@
+ALTER TABLE reports ADD COLUMN trace_parent VARCHAR;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+  DROP COLUMN failed_signin_attempts,
+  DROP COLUMN last_failed_signin_at,
+  DROP COLUMN locked_until;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+  ADD COLUMN failed_signin_attempts INT NOT NULL DEFAULT 0,
+  ADD COLUMN last_failed_signin_at TIMESTAMPTZ,
+  ADD COLUMN locked_until TIMESTAMPTZ;
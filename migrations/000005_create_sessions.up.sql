@@ -0,0 +1,16 @@
+CREATE TABLE sessions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  user_agent VARCHAR NOT NULL DEFAULT '',
+  ip_address VARCHAR NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_used_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+-- Existing refresh tokens cannot be attributed to a session; their users
+-- have to sign in again.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens ADD COLUMN session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+DELETE FROM refresh_tokens WHERE rotated_at IS NOT NULL;
+
+ALTER TABLE refresh_tokens DROP COLUMN rotated_at;
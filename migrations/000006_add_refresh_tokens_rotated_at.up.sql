@@ -0,0 +1,3 @@
+-- Rotated tokens are kept, marked with rotated_at, until they expire so that
+-- presenting one again can be detected as reuse.
+ALTER TABLE refresh_tokens ADD COLUMN rotated_at TIMESTAMPTZ;
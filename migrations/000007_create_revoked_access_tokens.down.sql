@@ -0,0 +1,5 @@
+ALTER TABLE sessions
+  DROP COLUMN access_token_id,
+  DROP COLUMN access_token_expires_at;
+
+DROP TABLE revoked_access_tokens;
@@ -0,0 +1,10 @@
+CREATE TABLE revoked_access_tokens (
+  jti UUID PRIMARY KEY,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- The latest access token issued for a session, so that revoking the session
+-- can also revoke it.
+ALTER TABLE sessions
+  ADD COLUMN access_token_id UUID,
+  ADD COLUMN access_token_expires_at TIMESTAMPTZ;
@@ -0,0 +1 @@
+ALTER TABLE refresh_tokens ALTER COLUMN expires_at SET DEFAULT CURRENT_TIMESTAMP + INTERVAL '1 day';
@@ -0,0 +1,3 @@
+-- Refresh token expiry is set from the token's exp claim, which follows
+-- JWT_REFRESH_TOKEN_TTL.
+ALTER TABLE refresh_tokens ALTER COLUMN expires_at DROP DEFAULT;
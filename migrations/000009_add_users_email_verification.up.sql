@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Accounts created before verification existed are trusted as they are.
+UPDATE users SET email_verified_at = created_at;
+
+-- Single-use tokens emailed to users, stored hashed, e.g. to verify their
+-- email address.
+CREATE TABLE user_tokens (
+  hashed_token VARCHAR(500) PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose VARCHAR(50) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX user_tokens_user_id_purpose_idx ON user_tokens (user_id, purpose);
@@ -0,0 +1,3 @@
+-- argon2id hashes in PHC format are longer than the base64 encoded bcrypt
+-- hashes this column was sized for.
+ALTER TABLE users ALTER COLUMN hashed_password TYPE VARCHAR(255);
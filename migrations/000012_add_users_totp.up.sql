@@ -0,0 +1,14 @@
+-- The TOTP secret is set on setup and only enforced once totp_enabled_at is
+-- set by a confirmed code. totp_last_step is the time step of the last code
+-- accepted, so that a code cannot be used twice.
+ALTER TABLE users
+  ADD COLUMN totp_secret VARCHAR(64),
+  ADD COLUMN totp_enabled_at TIMESTAMPTZ,
+  ADD COLUMN totp_last_step BIGINT;
+
+CREATE TABLE recovery_codes (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  hashed_code VARCHAR(500) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, hashed_code)
+);
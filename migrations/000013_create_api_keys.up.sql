@@ -0,0 +1,15 @@
+-- Keys are presented as ak_<prefix>_<secret>. The prefix identifies the key
+-- and only the hash of the secret is stored.
+CREATE TABLE api_keys (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(200) NOT NULL,
+  prefix VARCHAR(32) NOT NULL UNIQUE,
+  hashed_secret VARCHAR(500) NOT NULL,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
@@ -0,0 +1,26 @@
+CREATE TABLE roles (
+  name VARCHAR(50) PRIMARY KEY,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE role_permissions (
+  role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  permission VARCHAR(100) NOT NULL,
+  PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE user_roles (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles (name, description) VALUES ('admin', 'Operates the service on behalf of users');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'users:read'),
+  ('admin', 'users:write'),
+  ('admin', 'reports:read_all'),
+  ('admin', 'reports:write_all');
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS reports_org_id_created_at_idx;
+ALTER TABLE reports DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS invitations;
+DROP TABLE IF EXISTS memberships;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,34 @@
+CREATE TABLE organizations (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name VARCHAR(200) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE memberships (
+  org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role VARCHAR(20) NOT NULL CHECK (role IN ('member', 'admin')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX memberships_user_id_idx ON memberships (user_id);
+
+-- Invitations are accepted with a token mailed to email, only its hash is
+-- stored.
+CREATE TABLE invitations (
+  hashed_token VARCHAR(500) PRIMARY KEY,
+  org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  email VARCHAR(320) NOT NULL,
+  role VARCHAR(20) NOT NULL CHECK (role IN ('member', 'admin')),
+  invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX invitations_org_id_idx ON invitations (org_id);
+
+-- Reports without an organization are personal to their user.
+ALTER TABLE reports ADD COLUMN org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+CREATE INDEX reports_org_id_created_at_idx ON reports (org_id, created_at);
@@ -0,0 +1,32 @@
+-- Security-relevant events. Actor and target IDs are not foreign keys so that
+-- events outlive the users they are about.
+CREATE TABLE audit_events (
+  id BIGSERIAL PRIMARY KEY,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  event_type VARCHAR(100) NOT NULL,
+  outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('success', 'failure')),
+  reason VARCHAR(200) NOT NULL DEFAULT '',
+  actor_id UUID,
+  target_type VARCHAR(50) NOT NULL DEFAULT '',
+  target_id VARCHAR(100) NOT NULL DEFAULT '',
+  ip VARCHAR(45) NOT NULL DEFAULT '',
+  user_agent TEXT NOT NULL DEFAULT '',
+  request_id VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, created_at);
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id, created_at);
+
+-- The log is append-only.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+  BEFORE UPDATE OR DELETE ON audit_events
+  FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+INSERT INTO role_permissions (role, permission) VALUES ('admin', 'audit:read');
@@ -0,0 +1,2 @@
+DELETE FROM role_permissions WHERE permission IN ('invites:read', 'invites:write');
+DROP TABLE IF EXISTS invite_codes;
@@ -0,0 +1,15 @@
+-- Codes required to sign up under the invite signup policy. Only the hash of
+-- a code is stored.
+CREATE TABLE invite_codes (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  hashed_code VARCHAR(500) NOT NULL UNIQUE,
+  max_uses INTEGER NOT NULL CHECK (max_uses > 0),
+  uses INTEGER NOT NULL DEFAULT 0,
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMPTZ
+);
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'invites:read'),
+  ('admin', 'invites:write');
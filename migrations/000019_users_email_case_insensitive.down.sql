@@ -0,0 +1,11 @@
+DROP INDEX users_lower_email_key;
+
+-- Accounts moved aside get their emails back unless another account now
+-- has the exact same one.
+UPDATE users SET email = user_email_collisions.email
+FROM user_email_collisions
+WHERE users.id = user_email_collisions.user_id
+  AND NOT EXISTS (SELECT 1 FROM users other WHERE other.email = user_email_collisions.email);
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+DROP TABLE user_email_collisions;
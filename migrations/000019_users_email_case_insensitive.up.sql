@@ -0,0 +1,33 @@
+-- Emails differing only by case belong to the same person. Of each set of
+-- such accounts the oldest keeps the email. The others are moved aside to
+-- <user id>@duplicate.invalid, where no mail can be delivered, and listed in
+-- user_email_collisions with their original email.
+-- To merge one, move what should be kept to kept_user_id, then delete the
+-- moved account. Deleting either account removes the collision.
+CREATE TABLE user_email_collisions (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  kept_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email VARCHAR(320) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO user_email_collisions (user_id, kept_user_id, email)
+SELECT id, kept_user_id, email
+FROM (
+  SELECT id, email,
+    first_value(id) OVER accounts AS kept_user_id,
+    row_number() OVER accounts AS n
+  FROM users
+  WINDOW accounts AS (PARTITION BY lower(email) ORDER BY created_at, id)
+) ranked
+WHERE n > 1;
+
+UPDATE users SET email = id::text || '@duplicate.invalid'
+WHERE id IN (SELECT user_id FROM user_email_collisions);
+
+-- Domains are case-insensitive, keep them in lower case like new signups.
+UPDATE users SET email = split_part(email, '@', 1) || '@' || lower(split_part(email, '@', 2))
+WHERE email LIKE '%@%' AND email NOT LIKE '%@%@%' AND split_part(email, '@', 2) <> lower(split_part(email, '@', 2));
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX users_lower_email_key ON users (lower(email));
@@ -0,0 +1,5 @@
+-- Blobs left behind by deleted rows, removed from storage by a background job.
+CREATE TABLE blob_deletions (
+  path VARCHAR PRIMARY KEY,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
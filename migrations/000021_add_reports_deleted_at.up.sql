@@ -0,0 +1,2 @@
+ALTER TABLE reports ADD COLUMN deleted_at TIMESTAMPTZ;
+CREATE INDEX reports_deleted_at_idx ON reports (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE audit_events ALTER COLUMN target_id TYPE VARCHAR(100) USING left(target_id, 100);
@@ -0,0 +1,2 @@
+-- Failed signins for unknown accounts are targeted at the attempted email.
+ALTER TABLE audit_events ALTER COLUMN target_id TYPE VARCHAR(320);
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process. Counters are not shared between
+// replicas.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]Bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]Bucket),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, result := limit.Take(s.buckets[key], time.Now())
+	s.buckets[key] = bucket
+	return result, nil
+}
+
+func (s *MemoryStore) Cleanup(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, bucket := range s.buckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
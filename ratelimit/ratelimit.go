@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Period, refilled continuously, with
+// bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses a limit written as "<requests>/<period>", e.g. "10/1m".
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", s)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", s)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Bucket is the persisted state of a token bucket.
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Result describes the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the bucket is full again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until a token is available, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Take refills b for the time elapsed until now and takes a token from it if
+// one is available. A zero Bucket is treated as full.
+func (l Limit) Take(b Bucket, now time.Time) (Bucket, Result) {
+	tokens := float64(l.Requests)
+	if !b.UpdatedAt.IsZero() {
+		elapsed := now.Sub(b.UpdatedAt).Seconds()
+		tokens = math.Min(float64(l.Requests), b.Tokens+math.Max(0, elapsed)*l.rate())
+	}
+
+	result := Result{Limit: l.Requests}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / l.rate())
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = seconds((float64(l.Requests) - tokens) / l.rate())
+
+	return Bucket{Tokens: tokens, UpdatedAt: now}, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps token buckets by key.
+type Store interface {
+	// Take takes a token from the bucket identified by key.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Cleanup forgets buckets that have not been used since before.
+	Cleanup(ctx context.Context, before time.Time) error
+}
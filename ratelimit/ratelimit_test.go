@@ -0,0 +1,89 @@
+package ratelimit_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/ratelimit"
+)
+
+var _ = Describe("Limit", func() {
+	limit := ratelimit.Limit{Requests: 2, Period: time.Minute}
+
+	It("should parse a limit", func() {
+		l, err := ratelimit.ParseLimit("10/1m")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(l).To(Equal(ratelimit.Limit{Requests: 10, Period: time.Minute}))
+	})
+
+	It("should fail to parse an invalid limit", func() {
+		for _, s := range []string{"", "10", "0/1m", "ten/1m", "10/forever", "10/-1m"} {
+			_, err := ratelimit.ParseLimit(s)
+			Expect(err).To(HaveOccurred(), s)
+		}
+	})
+
+	It("should allow a burst then deny", func() {
+		now := time.Now()
+		bucket := ratelimit.Bucket{}
+
+		bucket, result := limit.Take(bucket, now)
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Remaining).To(Equal(1))
+
+		bucket, result = limit.Take(bucket, now)
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Remaining).To(Equal(0))
+
+		_, result = limit.Take(bucket, now)
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.RetryAfter).To(Equal(30 * time.Second))
+		Expect(result.ResetAfter).To(Equal(time.Minute))
+	})
+
+	It("should refill over time", func() {
+		now := time.Now()
+		bucket := ratelimit.Bucket{Tokens: 0, UpdatedAt: now}
+
+		_, result := limit.Take(bucket, now.Add(30*time.Second))
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Remaining).To(Equal(0))
+	})
+})
+
+var _ = Describe("MemoryStore", func() {
+	limit := ratelimit.Limit{Requests: 1, Period: time.Hour}
+
+	It("should keep separate buckets per key", func() {
+		ctx := context.Background()
+		store := ratelimit.NewMemoryStore()
+
+		result, err := store.Take(ctx, "a", limit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Allowed).To(BeTrue())
+
+		result, err = store.Take(ctx, "a", limit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Allowed).To(BeFalse())
+
+		result, err = store.Take(ctx, "b", limit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Allowed).To(BeTrue())
+	})
+
+	It("should forget idle buckets on cleanup", func() {
+		ctx := context.Background()
+		store := ratelimit.NewMemoryStore()
+
+		_, err := store.Take(ctx, "a", limit)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(store.Cleanup(ctx, time.Now().Add(time.Second))).To(Succeed())
+
+		result, err := store.Take(ctx, "a", limit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type APIKeyStore struct {
+	db *sqlx.DB
+}
+
+func NewAPIKeyStore(db *sql.DB) *APIKeyStore {
+	return &APIKeyStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+func (s *APIKeyStore) Create(ctx context.Context, userID uuid.UUID, name, prefix, secret string, scopes []string, expiresAt *time.Time) (_ *dto.APIKey, err error) {
+	ctx, done := instrument(ctx, "APIKeyStore.Create")
+	defer done(&err)
+
+	const dml = `INSERT INTO api_keys (user_id, name, prefix, hashed_secret, scopes, expires_at)
+	            VALUES ($1, $2, $3, $4, $5, $6) RETURNING *`
+
+	var apiKey dto.APIKey
+	if err := s.db.GetContext(ctx, &apiKey, dml, userID, name, prefix, dto.HashSecretToken(secret), pq.StringArray(scopes), expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return &apiKey, nil
+}
+
+func (s *APIKeyStore) ByPrefix(ctx context.Context, prefix string) (_ *dto.APIKey, err error) {
+	ctx, done := instrument(ctx, "APIKeyStore.ByPrefix")
+	defer done(&err)
+
+	const query = `SELECT * FROM api_keys WHERE prefix = $1`
+
+	var apiKey dto.APIKey
+	if err := s.db.GetContext(ctx, &apiKey, query, prefix); err != nil {
+		return nil, fmt.Errorf("failed to get api key by prefix: %w", err)
+	}
+	return &apiKey, nil
+}
+
+func (s *APIKeyStore) ByUserID(ctx context.Context, userID uuid.UUID) (_ []dto.APIKey, err error) {
+	ctx, done := instrument(ctx, "APIKeyStore.ByUserID")
+	defer done(&err)
+
+	const query = `SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`
+
+	var apiKeys []dto.APIKey
+	if err := s.db.SelectContext(ctx, &apiKeys, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get api keys for user %s: %w", userID, err)
+	}
+	return apiKeys, nil
+}
+
+// Touch records that a key was used. It writes at most once a minute per key
+// so that busy clients do not turn every request into an update.
+func (s *APIKeyStore) Touch(ctx context.Context, id uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "APIKeyStore.Touch")
+	defer done(&err)
+
+	const dml = `UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+	            WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')`
+
+	result, err := s.db.ExecContext(ctx, dml, id)
+	if err != nil {
+		return result, fmt.Errorf("failed to touch api key %s: %w", id, err)
+	}
+	return result, nil
+}
+
+func (s *APIKeyStore) Delete(ctx context.Context, userID, id uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "APIKeyStore.Delete")
+	defer done(&err)
+
+	const dml = `DELETE FROM api_keys WHERE user_id = $1 AND id = $2`
+
+	result, err := s.db.ExecContext(ctx, dml, userID, id)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete api key %s for user %s: %w", id, userID, err)
+	}
+	return result, nil
+}
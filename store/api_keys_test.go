@@ -0,0 +1,82 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("APIKeyStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var apiKeyStore *store.APIKeyStore
+	var userStore *store.UserStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		apiKeyStore = store.NewAPIKeyStore(env.DB)
+		userStore = store.NewUserStore(env.DB)
+	})
+
+	var user *dto.User
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+
+		ctx := context.Background()
+		user, err = userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should create an api key and find it by prefix", func() {
+		ctx := context.Background()
+		prefix, secret, err := dto.NewAPIKey()
+		Expect(err).NotTo(HaveOccurred())
+		expiresAt := time.Now().Add(time.Hour)
+
+		apiKey, err := apiKeyStore.Create(ctx, user.ID, "batch", prefix, secret, []string{dto.ScopeReportsRead}, &expiresAt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apiKey.HashedSecret).To(Equal(dto.HashSecretToken(secret)))
+		Expect(apiKey.HasScope(dto.ScopeReportsRead)).To(BeTrue())
+		Expect(apiKey.HasScope(dto.ScopeReportsWrite)).To(BeFalse())
+
+		found, err := apiKeyStore.ByPrefix(ctx, prefix)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.ID).To(Equal(apiKey.ID))
+		Expect(found.LastUsedAt).To(BeNil())
+
+		result, err := apiKeyStore.Touch(ctx, apiKey.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+
+		// Within a minute of the last use the key is not written again.
+		result, err = apiKeyStore.Touch(ctx, apiKey.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(0)))
+	})
+
+	It("should delete an api key", func() {
+		ctx := context.Background()
+		prefix, secret, err := dto.NewAPIKey()
+		Expect(err).NotTo(HaveOccurred())
+
+		apiKey, err := apiKeyStore.Create(ctx, user.ID, "batch", prefix, secret, []string{dto.ScopeReportsRead}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := apiKeyStore.Delete(ctx, user.ID, apiKey.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+
+		_, err = apiKeyStore.ByPrefix(ctx, prefix)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+})
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type AuditEventStore struct {
+	db *sqlx.DB
+}
+
+func NewAuditEventStore(db *sql.DB) *AuditEventStore {
+	return &AuditEventStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+// Record appends event to the log. The user agent and request ID come from
+// client headers, they are cut to fit their columns so that no client can
+// keep its requests out of the log.
+func (s *AuditEventStore) Record(ctx context.Context, event *dto.AuditEvent) (_ *dto.AuditEvent, err error) {
+	ctx, done := instrument(ctx, "AuditEventStore.Record")
+	defer done(&err)
+
+	const dml = `INSERT INTO audit_events
+	              (event_type, outcome, reason, actor_id, target_type, target_id, ip, user_agent, request_id)
+	            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING *`
+
+	var recorded dto.AuditEvent
+	if err := s.db.GetContext(ctx, &recorded, dml,
+		event.EventType,
+		event.Outcome,
+		event.Reason,
+		event.ActorID,
+		event.TargetType,
+		event.TargetID,
+		event.IP,
+		clientText(event.UserAgent, maxUserAgentLength),
+		clientText(event.RequestID, maxRequestIDLength),
+	); err != nil {
+		return nil, fmt.Errorf("failed to record %s audit event: %w", event.EventType, err)
+	}
+	return &recorded, nil
+}
+
+const (
+	maxUserAgentLength = 500
+	// The length of audit_events.request_id
+	maxRequestIDLength = 100
+)
+
+// clientText returns s, taken from a request, as valid UTF-8 without NUL
+// characters, which Postgres rejects, and cut to n characters.
+func clientText(s string, n int) string {
+	s = strings.ReplaceAll(strings.ToValidUTF8(s, "\uFFFD"), "\x00", "")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// AuditEventFilter narrows down the events returned by Search and Export,
+// zero fields match every event. Export ignores Limit and Offset.
+type AuditEventFilter struct {
+	EventType  string
+	Outcome    string
+	ActorID    *uuid.UUID
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+	Limit      int
+	Offset     int
+}
+
+// where returns the conditions matching filter and their arguments.
+func (filter AuditEventFilter) where() (string, []any) {
+	conditions := []string{"TRUE"}
+	args := []any{}
+	bind := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.EventType != "" {
+		bind("event_type = $%d", filter.EventType)
+	}
+	if filter.Outcome != "" {
+		bind("outcome = $%d", filter.Outcome)
+	}
+	if filter.ActorID != nil {
+		bind("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		bind("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		bind("target_id = $%d", filter.TargetID)
+	}
+	if filter.Since != nil {
+		bind("created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		bind("created_at < $%d", *filter.Until)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// Search returns the events matching filter, newest first.
+func (s *AuditEventStore) Search(ctx context.Context, filter AuditEventFilter) (_ []dto.AuditEvent, err error) {
+	ctx, done := instrument(ctx, "AuditEventStore.Search")
+	defer done(&err)
+
+	where, args := filter.where()
+	args = append(args, filter.Limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT * FROM audit_events WHERE %s ORDER BY id DESC LIMIT $%d OFFSET $%d`,
+		where, len(args)-1, len(args))
+
+	events := []dto.AuditEvent{}
+	if err := s.db.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search audit events: %w", err)
+	}
+	return events, nil
+}
+
+// Export calls f with every event matching filter, oldest first, without
+// loading them all in memory. It stops at the first error returned by f.
+func (s *AuditEventStore) Export(ctx context.Context, filter AuditEventFilter, f func(*dto.AuditEvent) error) (err error) {
+	ctx, done := instrument(ctx, "AuditEventStore.Export")
+	defer done(&err)
+
+	where, args := filter.where()
+	query := `SELECT * FROM audit_events WHERE ` + where + ` ORDER BY id`
+
+	rows, err := s.db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to export audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event dto.AuditEvent
+		if err := rows.StructScan(&event); err != nil {
+			return fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		if err := f(&event); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to export audit events: %w", err)
+	}
+	return nil
+}
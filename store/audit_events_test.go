@@ -0,0 +1,112 @@
+package store_test
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("AuditEventStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var auditEventStore *store.AuditEventStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		auditEventStore = store.NewAuditEventStore(env.DB)
+	})
+
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+	})
+
+	It("should record and search events", func() {
+		ctx := context.Background()
+		actorID := uuid.New()
+
+		event, err := auditEventStore.Record(ctx, &dto.AuditEvent{
+			EventType:  dto.AuditSignin,
+			Outcome:    dto.AuditOutcomeSuccess,
+			ActorID:    &actorID,
+			TargetType: dto.AuditTargetUser,
+			TargetID:   actorID.String(),
+			IP:         "127.0.0.1",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(event.ID).NotTo(BeZero())
+
+		_, err = auditEventStore.Record(ctx, &dto.AuditEvent{
+			EventType: dto.AuditSignin,
+			Outcome:   dto.AuditOutcomeFailure,
+			Reason:    "unknown_email",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		events, err := auditEventStore.Search(ctx, store.AuditEventFilter{EventType: dto.AuditSignin, Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Reason).To(Equal("unknown_email"))
+		Expect(events[0].ActorID).To(BeNil())
+
+		events, err = auditEventStore.Search(ctx, store.AuditEventFilter{ActorID: &actorID, Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].ID).To(Equal(event.ID))
+
+		since := time.Now().Add(time.Hour)
+		events, err = auditEventStore.Search(ctx, store.AuditEventFilter{Since: &since, Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(BeEmpty())
+	})
+
+	It("should record events with oversized client headers", func() {
+		ctx := context.Background()
+		event, err := auditEventStore.Record(ctx, &dto.AuditEvent{
+			EventType: dto.AuditSignin,
+			Outcome:   dto.AuditOutcomeFailure,
+			UserAgent: strings.Repeat("a", 10000) + "\xff\x00",
+			RequestID: strings.Repeat("é", 1000),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(event.UserAgent).To(Equal(strings.Repeat("a", 500)))
+		Expect(event.RequestID).To(Equal(strings.Repeat("é", 100)))
+	})
+
+	It("should export events oldest first", func() {
+		ctx := context.Background()
+		for _, eventType := range []string{dto.AuditSignup, dto.AuditSignin, dto.AuditSignoutAll} {
+			_, err := auditEventStore.Record(ctx, &dto.AuditEvent{EventType: eventType, Outcome: dto.AuditOutcomeSuccess})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		var eventTypes []string
+		err := auditEventStore.Export(ctx, store.AuditEventFilter{}, func(event *dto.AuditEvent) error {
+			eventTypes = append(eventTypes, event.EventType)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eventTypes).To(Equal([]string{dto.AuditSignup, dto.AuditSignin, dto.AuditSignoutAll}))
+	})
+
+	It("should not allow events to be changed", func() {
+		ctx := context.Background()
+		event, err := auditEventStore.Record(ctx, &dto.AuditEvent{EventType: dto.AuditSignup, Outcome: dto.AuditOutcomeSuccess})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = env.DB.Exec(`UPDATE audit_events SET outcome = 'failure' WHERE id = $1`, event.ID)
+		Expect(err).To(MatchError(ContainSubstring("append-only")))
+		_, err = env.DB.Exec(`DELETE FROM audit_events WHERE id = $1`, event.ID)
+		Expect(err).To(MatchError(ContainSubstring("append-only")))
+	})
+})
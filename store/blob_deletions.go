@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// BlobDeletionStore queues the paths of blobs to delete from storage once the
+// rows referencing them are gone.
+type BlobDeletionStore struct {
+	db *sqlx.DB
+}
+
+func NewBlobDeletionStore(db *sql.DB) *BlobDeletionStore {
+	return &BlobDeletionStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+// Pending returns up to limit queued paths, oldest first.
+func (s *BlobDeletionStore) Pending(ctx context.Context, limit int) (_ []string, err error) {
+	ctx, done := instrument(ctx, "BlobDeletionStore.Pending")
+	defer done(&err)
+
+	const query = `SELECT path FROM blob_deletions ORDER BY created_at LIMIT $1`
+
+	paths := []string{}
+	if err := s.db.SelectContext(ctx, &paths, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to get pending blob deletions: %w", err)
+	}
+	return paths, nil
+}
+
+// Done removes path from the queue once the blob is deleted.
+func (s *BlobDeletionStore) Done(ctx context.Context, path string) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "BlobDeletionStore.Done")
+	defer done(&err)
+
+	const dml = `DELETE FROM blob_deletions WHERE path = $1`
+
+	result, err := s.db.ExecContext(ctx, dml, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove blob deletion of %s: %w", path, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("BlobDeletionStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var dataStore *store.Store
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		dataStore = store.New(env.DB)
+	})
+
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+	})
+
+	It("should queue the report outputs of deleted users", func() {
+		ctx := context.Background()
+		user, err := dataStore.Users.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+		other, err := dataStore.Users.CreateUser(ctx, "other@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+
+		solo, err := dataStore.Organizations.Create(ctx, "solo", user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		shared, err := dataStore.Organizations.Create(ctx, "shared", other.ID)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dataStore.Organizations.AddMember(ctx, shared.ID, user.ID, dto.OrgRoleMember)
+		Expect(err).NotTo(HaveOccurred())
+
+		report, err := dataStore.Reports.Create(ctx, user.ID, &shared.ID, "monsters")
+		Expect(err).NotTo(HaveOccurred())
+		outputFilePath := "users/test/report.csv"
+		report.OutputFilePath = &outputFilePath
+		_, err = dataStore.Reports.Update(ctx, report)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dataStore.Reports.Create(ctx, user.ID, nil, "monsters")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = dataStore.Users.Delete(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = dataStore.Users.ByID(ctx, user.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		reports, err := dataStore.Reports.ByUserID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(BeEmpty())
+
+		orgs, err := dataStore.Organizations.ByUserID(ctx, other.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orgs).To(HaveLen(1))
+		var soloExists bool
+		err = env.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM organizations WHERE id = $1)`, solo.ID).Scan(&soloExists)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(soloExists).To(BeFalse())
+
+		paths, err := dataStore.BlobDeletions.Pending(ctx, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(Equal([]string{outputFilePath}))
+
+		_, err = dataStore.BlobDeletions.Done(ctx, outputFilePath)
+		Expect(err).NotTo(HaveOccurred())
+		paths, err = dataStore.BlobDeletions.Pending(ctx, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(BeEmpty())
+	})
+
+	It("should not delete the last admin of a shared organization", func() {
+		ctx := context.Background()
+		user, err := dataStore.Users.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+		other, err := dataStore.Users.CreateUser(ctx, "other@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+
+		shared, err := dataStore.Organizations.Create(ctx, "shared", user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dataStore.Organizations.AddMember(ctx, shared.ID, other.ID, dto.OrgRoleMember)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = dataStore.Users.Delete(ctx, user.ID)
+		Expect(err).To(MatchError(store.ErrLastAdmin))
+		_, err = dataStore.Users.ByID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = dataStore.Organizations.UpdateMemberRole(ctx, shared.ID, other.ID, dto.OrgRoleAdmin)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dataStore.Users.Delete(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
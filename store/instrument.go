@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/talvor/asyncapi/metrics"
+	"github.com/talvor/asyncapi/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrument starts a span for a store method and returns the context to run
+// the query with, plus a function that ends the span and records the method's
+// latency. It is meant to be deferred with a pointer to the method's named
+// error result:
+//
+//	ctx, done := instrument(ctx, "UserStore.ByID")
+//	defer done(&err)
+func instrument(ctx context.Context, method string) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL),
+	)
+
+	return ctx, func(err *error) {
+		metrics.ObserveQuery(method, start, err)
+		tracing.End(span, *err)
+	}
+}
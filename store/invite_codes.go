@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type InviteCodeStore struct {
+	db *sqlx.DB
+}
+
+func NewInviteCodeStore(db *sql.DB) *InviteCodeStore {
+	return &InviteCodeStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+// Create stores the hash of code, to be used up to maxUses times before
+// expiresAt, or indefinitely when expiresAt is nil.
+func (s *InviteCodeStore) Create(ctx context.Context, code string, maxUses int, createdBy uuid.UUID, expiresAt *time.Time) (_ *dto.InviteCode, err error) {
+	ctx, done := instrument(ctx, "InviteCodeStore.Create")
+	defer done(&err)
+
+	const dml = `INSERT INTO invite_codes (hashed_code, max_uses, created_by, expires_at) VALUES ($1, $2, $3, $4) RETURNING *`
+
+	var inviteCode dto.InviteCode
+	if err := s.db.GetContext(ctx, &inviteCode, dml, dto.HashSecretToken(code), maxUses, createdBy, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to insert invite code: %w", err)
+	}
+	return &inviteCode, nil
+}
+
+func (s *InviteCodeStore) List(ctx context.Context) (_ []dto.InviteCode, err error) {
+	ctx, done := instrument(ctx, "InviteCodeStore.List")
+	defer done(&err)
+
+	const query = `SELECT * FROM invite_codes ORDER BY created_at DESC`
+
+	inviteCodes := []dto.InviteCode{}
+	if err := s.db.SelectContext(ctx, &inviteCodes, query); err != nil {
+		return nil, fmt.Errorf("failed to list invite codes: %w", err)
+	}
+	return inviteCodes, nil
+}
+
+// Use counts a use of code and returns it. It fails with sql.ErrNoRows when
+// the code does not exist, has expired or has been used up.
+func (s *InviteCodeStore) Use(ctx context.Context, code string) (_ *dto.InviteCode, err error) {
+	ctx, done := instrument(ctx, "InviteCodeStore.Use")
+	defer done(&err)
+
+	const dml = `UPDATE invite_codes SET uses = uses + 1
+	            WHERE hashed_code = $1 AND uses < max_uses
+	            AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP) RETURNING *`
+
+	var inviteCode dto.InviteCode
+	if err := s.db.GetContext(ctx, &inviteCode, dml, dto.HashSecretToken(code)); err != nil {
+		return nil, fmt.Errorf("failed to use invite code: %w", err)
+	}
+	return &inviteCode, nil
+}
+
+// Release gives back a use of an invite code whose signup did not complete.
+func (s *InviteCodeStore) Release(ctx context.Context, id uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "InviteCodeStore.Release")
+	defer done(&err)
+
+	const dml = `UPDATE invite_codes SET uses = uses - 1 WHERE id = $1 AND uses > 0`
+
+	result, err := s.db.ExecContext(ctx, dml, id)
+	if err != nil {
+		return result, fmt.Errorf("failed to release invite code %s: %w", id, err)
+	}
+	return result, nil
+}
+
+func (s *InviteCodeStore) Delete(ctx context.Context, id uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "InviteCodeStore.Delete")
+	defer done(&err)
+
+	const dml = `DELETE FROM invite_codes WHERE id = $1`
+
+	result, err := s.db.ExecContext(ctx, dml, id)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete invite code %s: %w", id, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("InviteCodeStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var inviteCodeStore *store.InviteCodeStore
+	var userStore *store.UserStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		inviteCodeStore = store.NewInviteCodeStore(env.DB)
+		userStore = store.NewUserStore(env.DB)
+	})
+
+	var admin *dto.User
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+
+		ctx := context.Background()
+		admin, err = userStore.CreateUser(ctx, "admin@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should allow up to max uses", func() {
+		ctx := context.Background()
+		code, err := dto.NewSecretToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		inviteCode, err := inviteCodeStore.Create(ctx, code, 2, admin.ID, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inviteCode.HashedCode).To(Equal(dto.HashSecretToken(code)))
+
+		_, err = inviteCodeStore.Use(ctx, code)
+		Expect(err).NotTo(HaveOccurred())
+		used, err := inviteCodeStore.Use(ctx, code)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(used.Uses).To(Equal(2))
+
+		_, err = inviteCodeStore.Use(ctx, code)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		_, err = inviteCodeStore.Release(ctx, inviteCode.ID)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = inviteCodeStore.Use(ctx, code)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should not use expired or deleted codes", func() {
+		ctx := context.Background()
+		expiresAt := time.Now().Add(-time.Minute)
+		expired, err := dto.NewSecretToken()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = inviteCodeStore.Create(ctx, expired, 1, admin.ID, &expiresAt)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = inviteCodeStore.Use(ctx, expired)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		deleted, err := dto.NewSecretToken()
+		Expect(err).NotTo(HaveOccurred())
+		inviteCode, err := inviteCodeStore.Create(ctx, deleted, 1, admin.ID, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = inviteCodeStore.Delete(ctx, inviteCode.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = inviteCodeStore.Use(ctx, deleted)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		inviteCodes, err := inviteCodeStore.List(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inviteCodes).To(HaveLen(1))
+	})
+})
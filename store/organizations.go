@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type OrganizationStore struct {
+	db *sqlx.DB
+}
+
+func NewOrganizationStore(db *sql.DB) *OrganizationStore {
+	return &OrganizationStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+// Create creates an organization with ownerID as its first admin.
+func (s *OrganizationStore) Create(ctx context.Context, name string, ownerID uuid.UUID) (_ *dto.Organization, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.Create")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var org dto.Organization
+	if err := tx.GetContext(ctx, &org, `INSERT INTO organizations (name) VALUES ($1) RETURNING *`, name); err != nil {
+		return nil, fmt.Errorf("failed to insert organization: %w", err)
+	}
+
+	const dml = `INSERT INTO memberships (org_id, user_id, role) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, dml, org.ID, ownerID, dto.OrgRoleAdmin); err != nil {
+		return nil, fmt.Errorf("failed to add owner %s to organization %s: %w", ownerID, org.ID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit organization: %w", err)
+	}
+	return &org, nil
+}
+
+func (s *OrganizationStore) ByUserID(ctx context.Context, userID uuid.UUID) (_ []dto.UserOrganization, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.ByUserID")
+	defer done(&err)
+
+	const query = `SELECT o.*, m.role FROM organizations o
+	              JOIN memberships m ON m.org_id = o.id
+	              WHERE m.user_id = $1 ORDER BY o.name`
+
+	orgs := []dto.UserOrganization{}
+	if err := s.db.SelectContext(ctx, &orgs, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get organizations for user %s: %w", userID, err)
+	}
+	return orgs, nil
+}
+
+// Membership returns the membership of userID in orgID, failing with
+// sql.ErrNoRows when they are not a member.
+func (s *OrganizationStore) Membership(ctx context.Context, orgID, userID uuid.UUID) (_ *dto.Membership, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.Membership")
+	defer done(&err)
+
+	const query = `SELECT * FROM memberships WHERE org_id = $1 AND user_id = $2`
+
+	var membership dto.Membership
+	if err := s.db.GetContext(ctx, &membership, query, orgID, userID); err != nil {
+		return nil, fmt.Errorf("failed to get membership of user %s in organization %s: %w", userID, orgID, err)
+	}
+	return &membership, nil
+}
+
+func (s *OrganizationStore) Members(ctx context.Context, orgID uuid.UUID) (_ []dto.Member, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.Members")
+	defer done(&err)
+
+	const query = `SELECT m.*, u.email, u.name FROM memberships m
+	              JOIN users u ON u.id = m.user_id
+	              WHERE m.org_id = $1 ORDER BY u.email`
+
+	members := []dto.Member{}
+	if err := s.db.SelectContext(ctx, &members, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to get members of organization %s: %w", orgID, err)
+	}
+	return members, nil
+}
+
+// AddMember adds userID to orgID with role. Existing members keep their role.
+func (s *OrganizationStore) AddMember(ctx context.Context, orgID, userID uuid.UUID, role string) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.AddMember")
+	defer done(&err)
+
+	const dml = `INSERT INTO memberships (org_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	result, err := s.db.ExecContext(ctx, dml, orgID, userID, role)
+	if err != nil {
+		return result, fmt.Errorf("failed to add user %s to organization %s: %w", userID, orgID, err)
+	}
+	return result, nil
+}
+
+// UpdateMemberRole changes the role of userID in orgID. The last admin of an
+// organization cannot be demoted, in which case no row is affected.
+func (s *OrganizationStore) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role string) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.UpdateMemberRole")
+	defer done(&err)
+
+	const dml = `UPDATE memberships SET role = $3
+	            WHERE org_id = $1 AND user_id = $2
+	            AND ($3 = 'admin' OR EXISTS (
+	              SELECT 1 FROM memberships WHERE org_id = $1 AND user_id <> $2 AND role = 'admin'
+	            ))`
+
+	result, err := s.db.ExecContext(ctx, dml, orgID, userID, role)
+	if err != nil {
+		return result, fmt.Errorf("failed to update role of user %s in organization %s: %w", userID, orgID, err)
+	}
+	return result, nil
+}
+
+// RemoveMember removes userID from orgID. The last admin of an organization
+// cannot be removed, in which case no row is affected.
+func (s *OrganizationStore) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.RemoveMember")
+	defer done(&err)
+
+	const dml = `DELETE FROM memberships
+	            WHERE org_id = $1 AND user_id = $2
+	            AND (role <> 'admin' OR EXISTS (
+	              SELECT 1 FROM memberships WHERE org_id = $1 AND user_id <> $2 AND role = 'admin'
+	            ))`
+
+	result, err := s.db.ExecContext(ctx, dml, orgID, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to remove user %s from organization %s: %w", userID, orgID, err)
+	}
+	return result, nil
+}
+
+// CreateInvitation stores the hash of token, to be used once by email to join
+// orgID with role before expiresAt.
+func (s *OrganizationStore) CreateInvitation(ctx context.Context, orgID uuid.UUID, email, role string, invitedBy uuid.UUID, token string, expiresAt time.Time) (_ *dto.Invitation, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.CreateInvitation")
+	defer done(&err)
+
+	const dml = `INSERT INTO invitations (hashed_token, org_id, email, role, invited_by, expires_at)
+	            VALUES ($1, $2, $3, $4, $5, $6) RETURNING *`
+
+	var invitation dto.Invitation
+	if err := s.db.GetContext(ctx, &invitation, dml, dto.HashSecretToken(token), orgID, email, role, invitedBy, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to insert invitation to organization %s: %w", orgID, err)
+	}
+	return &invitation, nil
+}
+
+// ErrAlreadyMember is returned by AcceptInvitation when the user is already a
+// member of the organization.
+var ErrAlreadyMember = errors.New("already a member of the organization")
+
+// AcceptInvitation consumes the invitation for token and adds userID to its
+// organization with the invited role. It fails with sql.ErrNoRows when there
+// is no unexpired invitation for token sent to email, so each invitation can
+// be used once, and only by its recipient. It fails with ErrAlreadyMember,
+// leaving the invitation unused, when userID is already a member.
+func (s *OrganizationStore) AcceptInvitation(ctx context.Context, token, email string, userID uuid.UUID) (_ *dto.Invitation, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.AcceptInvitation")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const consume = `DELETE FROM invitations
+	                WHERE hashed_token = $1 AND lower(email) = lower($2) AND expires_at > CURRENT_TIMESTAMP RETURNING *`
+
+	var invitation dto.Invitation
+	if err := tx.GetContext(ctx, &invitation, consume, dto.HashSecretToken(token), email); err != nil {
+		return nil, fmt.Errorf("failed to consume invitation: %w", err)
+	}
+
+	const addMember = `INSERT INTO memberships (org_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	result, err := tx.ExecContext(ctx, addMember, invitation.OrgID, userID, invitation.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add user %s to organization %s: %w", userID, invitation.OrgID, err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return nil, fmt.Errorf("failed to add user %s to organization %s: %w", userID, invitation.OrgID, ErrAlreadyMember)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invitation acceptance: %w", err)
+	}
+	return &invitation, nil
+}
+
+func (s *OrganizationStore) DeleteExpiredInvitations(ctx context.Context, before time.Time) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "OrganizationStore.DeleteExpiredInvitations")
+	defer done(&err)
+
+	const dml = `DELETE FROM invitations WHERE expires_at < $1`
+
+	result, err := s.db.ExecContext(ctx, dml, before)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete expired invitations: %w", err)
+	}
+	return result, nil
+}
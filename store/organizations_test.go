@@ -0,0 +1,150 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("OrganizationStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var orgStore *store.OrganizationStore
+	var userStore *store.UserStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		orgStore = store.NewOrganizationStore(env.DB)
+		userStore = store.NewUserStore(env.DB)
+	})
+
+	var owner, teammate *dto.User
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+
+		ctx := context.Background()
+		owner, err = userStore.CreateUser(ctx, "owner@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+		teammate, err = userStore.CreateUser(ctx, "teammate@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should make the owner an admin", func() {
+		ctx := context.Background()
+		org, err := orgStore.Create(ctx, "analytics", owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		membership, err := orgStore.Membership(ctx, org.ID, owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(membership.IsAdmin()).To(BeTrue())
+
+		orgs, err := orgStore.ByUserID(ctx, owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orgs).To(HaveLen(1))
+		Expect(orgs[0].Name).To(Equal("analytics"))
+		Expect(orgs[0].Role).To(Equal(dto.OrgRoleAdmin))
+
+		_, err = orgStore.Membership(ctx, org.ID, teammate.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should keep at least one admin", func() {
+		ctx := context.Background()
+		org, err := orgStore.Create(ctx, "analytics", owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = orgStore.AddMember(ctx, org.ID, teammate.ID, dto.OrgRoleMember)
+		Expect(err).NotTo(HaveOccurred())
+
+		members, err := orgStore.Members(ctx, org.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(members).To(HaveLen(2))
+
+		result, err := orgStore.UpdateMemberRole(ctx, org.ID, owner.ID, dto.OrgRoleMember)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(0)))
+		result, err = orgStore.RemoveMember(ctx, org.ID, owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(0)))
+
+		result, err = orgStore.UpdateMemberRole(ctx, org.ID, teammate.ID, dto.OrgRoleAdmin)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+		result, err = orgStore.RemoveMember(ctx, org.ID, owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+	})
+
+	It("should accept an invitation once, by its recipient", func() {
+		ctx := context.Background()
+		org, err := orgStore.Create(ctx, "analytics", owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		token, err := dto.NewSecretToken()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = orgStore.CreateInvitation(ctx, org.ID, teammate.Email, dto.OrgRoleMember, owner.ID, token, time.Now().Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = orgStore.AcceptInvitation(ctx, token, owner.Email, owner.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		invitation, err := orgStore.AcceptInvitation(ctx, token, "Teammate@testing.com", teammate.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(invitation.OrgID).To(Equal(org.ID))
+		Expect(invitation.Role).To(Equal(dto.OrgRoleMember))
+
+		membership, err := orgStore.Membership(ctx, org.ID, teammate.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(membership.Role).To(Equal(dto.OrgRoleMember))
+
+		_, err = orgStore.AcceptInvitation(ctx, token, teammate.Email, teammate.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should keep invitations sent to existing members", func() {
+		ctx := context.Background()
+		org, err := orgStore.Create(ctx, "analytics", owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = orgStore.AddMember(ctx, org.ID, teammate.ID, dto.OrgRoleMember)
+		Expect(err).NotTo(HaveOccurred())
+
+		token, err := dto.NewSecretToken()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = orgStore.CreateInvitation(ctx, org.ID, teammate.Email, dto.OrgRoleAdmin, owner.ID, token, time.Now().Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = orgStore.AcceptInvitation(ctx, token, teammate.Email, teammate.ID)
+		Expect(err).To(MatchError(store.ErrAlreadyMember))
+
+		result, err := orgStore.DeleteExpiredInvitations(ctx, time.Now().Add(2*time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+	})
+
+	It("should not consume expired invitations", func() {
+		ctx := context.Background()
+		org, err := orgStore.Create(ctx, "analytics", owner.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		token, err := dto.NewSecretToken()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = orgStore.CreateInvitation(ctx, org.ID, teammate.Email, dto.OrgRoleMember, owner.ID, token, time.Now().Add(-time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = orgStore.AcceptInvitation(ctx, token, teammate.Email, teammate.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		result, err := orgStore.DeleteExpiredInvitations(ctx, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+	})
+})
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/ratelimit"
+)
+
+// RateLimitStore keeps rate limit buckets in Postgres so that every API
+// replica shares the same counters.
+type RateLimitStore struct {
+	db *sqlx.DB
+}
+
+func NewRateLimitStore(db *sql.DB) *RateLimitStore {
+	return &RateLimitStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+type rateLimitBucket struct {
+	Key       string    `db:"key"`
+	Tokens    float64   `db:"tokens"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (_ ratelimit.Result, err error) {
+	ctx, done := instrument(ctx, "RateLimitStore.Take")
+	defer done(&err)
+
+	// Create a full bucket first so that concurrent requests for a new key
+	// serialize on the row lock below.
+	const insert = `INSERT INTO rate_limit_buckets (key, tokens, updated_at) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING`
+	const query = `SELECT * FROM rate_limit_buckets WHERE key = $1 FOR UPDATE`
+	const dml = `UPDATE rate_limit_buckets SET tokens = $1, updated_at = $2 WHERE key = $3`
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, insert, key, limit.Requests, now); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to create rate limit bucket %s: %w", key, err)
+	}
+
+	var row rateLimitBucket
+	if err := tx.GetContext(ctx, &row, query, key); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to get rate limit bucket %s: %w", key, err)
+	}
+
+	bucket, result := limit.Take(ratelimit.Bucket{Tokens: row.Tokens, UpdatedAt: row.UpdatedAt}, now)
+
+	if _, err := tx.ExecContext(ctx, dml, bucket.Tokens, bucket.UpdatedAt, key); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to update rate limit bucket %s: %w", key, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("failed to commit rate limit bucket %s: %w", key, err)
+	}
+	return result, nil
+}
+
+func (s *RateLimitStore) Cleanup(ctx context.Context, before time.Time) (err error) {
+	ctx, done := instrument(ctx, "RateLimitStore.Cleanup")
+	defer done(&err)
+
+	const dml = `DELETE FROM rate_limit_buckets WHERE updated_at < $1`
+
+	if _, err := s.db.ExecContext(ctx, dml, before); err != nil {
+		return fmt.Errorf("failed to delete stale rate limit buckets: %w", err)
+	}
+	return nil
+}
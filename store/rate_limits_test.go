@@ -0,0 +1,67 @@
+package store_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/ratelimit"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("RateLimitStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var rateLimitStore *store.RateLimitStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		rateLimitStore = store.NewRateLimitStore(env.DB)
+	})
+
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+	})
+
+	limit := ratelimit.Limit{Requests: 2, Period: time.Hour}
+
+	It("should take tokens until the bucket is empty", func() {
+		ctx := context.Background()
+
+		result, err := rateLimitStore.Take(ctx, "test", limit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Allowed).To(BeTrue())
+		Expect(result.Remaining).To(Equal(1))
+
+		result, err = rateLimitStore.Take(ctx, "test", limit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Allowed).To(BeTrue())
+
+		result, err = rateLimitStore.Take(ctx, "test", limit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Allowed).To(BeFalse())
+		Expect(result.RetryAfter).To(BeNumerically(">", 0))
+	})
+
+	It("should reset idle buckets on cleanup", func() {
+		ctx := context.Background()
+
+		for range 2 {
+			_, err := rateLimitStore.Take(ctx, "test", limit)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		err := rateLimitStore.Cleanup(ctx, time.Now().Add(time.Second))
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := rateLimitStore.Take(ctx, "test", limit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Allowed).To(BeTrue())
+	})
+})
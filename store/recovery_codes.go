@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type RecoveryCodeStore struct {
+	db *sqlx.DB
+}
+
+func NewRecoveryCodeStore(db *sql.DB) *RecoveryCodeStore {
+	return &RecoveryCodeStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+// Replace stores the hashes of codes as the only recovery codes of a user.
+func (s *RecoveryCodeStore) Replace(ctx context.Context, userID uuid.UUID, codes []string) (err error) {
+	ctx, done := instrument(ctx, "RecoveryCodeStore.Replace")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes for user %s: %w", userID, err)
+	}
+
+	for _, code := range codes {
+		const dml = `INSERT INTO recovery_codes (user_id, hashed_code) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, dml, userID, dto.HashRecoveryCode(code)); err != nil {
+			return fmt.Errorf("failed to insert recovery code for user %s: %w", userID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return nil
+}
+
+// Consume deletes code and returns it. It fails with sql.ErrNoRows when the
+// user has no such code, so each code can be used once.
+func (s *RecoveryCodeStore) Consume(ctx context.Context, userID uuid.UUID, code string) (_ *dto.RecoveryCode, err error) {
+	ctx, done := instrument(ctx, "RecoveryCodeStore.Consume")
+	defer done(&err)
+
+	const dml = `DELETE FROM recovery_codes WHERE user_id = $1 AND hashed_code = $2 RETURNING *`
+
+	var recoveryCode dto.RecoveryCode
+	if err := s.db.GetContext(ctx, &recoveryCode, dml, userID, dto.HashRecoveryCode(code)); err != nil {
+		return nil, fmt.Errorf("failed to consume recovery code for user %s: %w", userID, err)
+	}
+	return &recoveryCode, nil
+}
+
+func (s *RecoveryCodeStore) DeleteUserCodes(ctx context.Context, userID uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "RecoveryCodeStore.DeleteUserCodes")
+	defer done(&err)
+
+	const dml = `DELETE FROM recovery_codes WHERE user_id = $1`
+
+	result, err := s.db.ExecContext(ctx, dml, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete recovery codes for user %s: %w", userID, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("RecoveryCodeStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var recoveryCodeStore *store.RecoveryCodeStore
+	var userStore *store.UserStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		recoveryCodeStore = store.NewRecoveryCodeStore(env.DB)
+		userStore = store.NewUserStore(env.DB)
+	})
+
+	var user *dto.User
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+
+		ctx := context.Background()
+		user, err = userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should consume a recovery code once", func() {
+		ctx := context.Background()
+		code, err := dto.NewRecoveryCode()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(recoveryCodeStore.Replace(ctx, user.ID, []string{code})).To(Succeed())
+
+		_, err = recoveryCodeStore.Consume(ctx, user.ID, strings.ToUpper(code))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = recoveryCodeStore.Consume(ctx, user.ID, code)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should replace previous recovery codes", func() {
+		ctx := context.Background()
+		oldCode, err := dto.NewRecoveryCode()
+		Expect(err).NotTo(HaveOccurred())
+		newCode, err := dto.NewRecoveryCode()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(recoveryCodeStore.Replace(ctx, user.ID, []string{oldCode})).To(Succeed())
+		Expect(recoveryCodeStore.Replace(ctx, user.ID, []string{newCode})).To(Succeed())
+
+		_, err = recoveryCodeStore.Consume(ctx, user.ID, oldCode)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		_, err = recoveryCodeStore.Consume(ctx, user.ID, newCode)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -22,8 +23,55 @@ func NewRefreshTokenStore(db *sql.DB) *RefreshTokenStore {
 	}
 }
 
-func (s *RefreshTokenStore) Create(ctx context.Context, userID uuid.UUID, token *jwt.Token) (*dto.RefreshToken, error) {
-	const dml = `INSERT INTO refresh_tokens (user_id, hashed_token, expires_at) VALUES ($1, $2, $3) RETURNING *`
+func (s *RefreshTokenStore) Create(ctx context.Context, userID, sessionID uuid.UUID, token *jwt.Token) (_ *dto.RefreshToken, err error) {
+	ctx, done := instrument(ctx, "RefreshTokenStore.Create")
+	defer done(&err)
+
+	return createRefreshToken(ctx, s.db, userID, sessionID, token)
+}
+
+// Rotate marks oldToken as rotated and adds newToken to the same session.
+// It fails with sql.ErrNoRows when oldToken was already rotated, which means
+// it is being reused.
+func (s *RefreshTokenStore) Rotate(ctx context.Context, userID, sessionID uuid.UUID, oldToken, newToken *jwt.Token) (_ *dto.RefreshToken, err error) {
+	ctx, done := instrument(ctx, "RefreshTokenStore.Rotate")
+	defer done(&err)
+
+	const dml = `UPDATE refresh_tokens SET rotated_at = CURRENT_TIMESTAMP
+	            WHERE user_id = $1 AND session_id = $2 AND hashed_token = $3 AND rotated_at IS NULL;`
+
+	hashedToken, err := dto.HashToken(oldToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hashing token: %w", err)
+	}
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, dml, userID, sessionID, hashedToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark refresh token record rotated: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return nil, fmt.Errorf("failed to mark refresh token record rotated: %w", sql.ErrNoRows)
+	}
+
+	refreshToken, err := createRefreshToken(ctx, tx, userID, sessionID, newToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit refresh token rotation: %w", err)
+	}
+	return refreshToken, nil
+}
+
+func createRefreshToken(ctx context.Context, q sqlx.QueryerContext, userID, sessionID uuid.UUID, token *jwt.Token) (*dto.RefreshToken, error) {
+	const dml = `INSERT INTO refresh_tokens (user_id, session_id, hashed_token, expires_at) VALUES ($1, $2, $3, $4) RETURNING *`
 
 	hashedToken, err := dto.HashToken(token)
 	if err != nil {
@@ -36,13 +84,16 @@ func (s *RefreshTokenStore) Create(ctx context.Context, userID uuid.UUID, token
 	}
 
 	var refreshToken dto.RefreshToken
-	if err := s.db.GetContext(ctx, &refreshToken, dml, userID, hashedToken, expiresAt.Time); err != nil {
+	if err := sqlx.GetContext(ctx, q, &refreshToken, dml, userID, sessionID, hashedToken, expiresAt.Time); err != nil {
 		return nil, fmt.Errorf("failed to insert refresh token record: %w", err)
 	}
 	return &refreshToken, nil
 }
 
-func (s *RefreshTokenStore) ByPrimaryKey(ctx context.Context, userID uuid.UUID, token *jwt.Token) (*dto.RefreshToken, error) {
+func (s *RefreshTokenStore) ByPrimaryKey(ctx context.Context, userID uuid.UUID, token *jwt.Token) (_ *dto.RefreshToken, err error) {
+	ctx, done := instrument(ctx, "RefreshTokenStore.ByPrimaryKey")
+	defer done(&err)
+
 	const query = `SELECT * FROM refresh_tokens WHERE user_id = $1 AND hashed_token = $2;`
 
 	hashedToken, err := dto.HashToken(token)
@@ -57,7 +108,28 @@ func (s *RefreshTokenStore) ByPrimaryKey(ctx context.Context, userID uuid.UUID,
 	return &refreshToken, nil
 }
 
-func (s *RefreshTokenStore) DeleteUserTokens(ctx context.Context, userID uuid.UUID) (sql.Result, error) {
+func (s *RefreshTokenStore) Delete(ctx context.Context, userID uuid.UUID, token *jwt.Token) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "RefreshTokenStore.Delete")
+	defer done(&err)
+
+	const dml = `DELETE FROM refresh_tokens WHERE user_id = $1 AND hashed_token = $2;`
+
+	hashedToken, err := dto.HashToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hashing token: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, dml, userID, hashedToken)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete refresh token record: %w", err)
+	}
+	return result, nil
+}
+
+func (s *RefreshTokenStore) DeleteUserTokens(ctx context.Context, userID uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "RefreshTokenStore.DeleteUserTokens")
+	defer done(&err)
+
 	const dml = `DELETE FROM refresh_tokens WHERE user_id = $1;`
 
 	result, err := s.db.ExecContext(ctx, dml, userID)
@@ -67,11 +139,17 @@ func (s *RefreshTokenStore) DeleteUserTokens(ctx context.Context, userID uuid.UU
 	return result, nil
 }
 
-func (s *RefreshTokenStore) DeleteUserTokensThenCreate(ctx context.Context, userID uuid.UUID, token *jwt.Token) (*dto.RefreshToken, error) {
-	_, err := s.DeleteUserTokens(ctx, userID)
+// DeleteExpired deletes refresh tokens, rotated or not, that expired before
+// before. They can no longer be presented, so there is nothing to detect.
+func (s *RefreshTokenStore) DeleteExpired(ctx context.Context, before time.Time) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "RefreshTokenStore.DeleteExpired")
+	defer done(&err)
+
+	const dml = `DELETE FROM refresh_tokens WHERE expires_at < $1;`
+
+	result, err := s.db.ExecContext(ctx, dml, before)
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete user refresh tokens: %w", err)
+		return result, fmt.Errorf("failed to delete expired refresh tokens: %w", err)
 	}
-
-	return s.Create(ctx, userID, token)
+	return result, nil
 }
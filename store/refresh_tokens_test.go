@@ -2,6 +2,7 @@ package store_test
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -16,6 +17,7 @@ var _ = Describe("RefreshTokenStore", Ordered, func() {
 	var env *fixtures.TestEnv
 	var refreshTokenStore *store.RefreshTokenStore
 	var userStore *store.UserStore
+	var sessionStore *store.SessionStore
 	var jwtManager *apiserver.JwtManager
 
 	BeforeAll(func() {
@@ -25,10 +27,13 @@ var _ = Describe("RefreshTokenStore", Ordered, func() {
 		env = te
 		refreshTokenStore = store.NewRefreshTokenStore(env.DB)
 		userStore = store.NewUserStore(env.DB)
-		jwtManager = apiserver.NewJwtManager(env.Config)
+		sessionStore = store.NewSessionStore(env.DB)
+		jwtManager, err = apiserver.NewJwtManager(env.Config)
+		Expect(err).NotTo(HaveOccurred())
 	})
 
 	var user *dto.User
+	var session *dto.Session
 	BeforeEach(func() {
 		err := env.SetupDB()
 		Expect(err).NotTo(HaveOccurred())
@@ -37,21 +42,25 @@ var _ = Describe("RefreshTokenStore", Ordered, func() {
 		ctx := context.Background()
 		user, err = userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
 		Expect(err).NotTo(HaveOccurred())
+
+		session, err = sessionStore.Create(ctx, user.ID, "test-agent", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
 	})
 
 	It("should create a refresh token", func() {
 		ctx := context.Background()
 		now := time.Now()
 
-		tokenPair, err := jwtManager.GenerateTokenPair(user.ID)
+		tokenPair, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 
 		expiresAt, err := tokenPair.RefreshToken.Claims.GetExpirationTime()
 		Expect(err).NotTo(HaveOccurred())
 
-		refreshToken, err := refreshTokenStore.Create(ctx, user.ID, tokenPair.RefreshToken)
+		refreshToken, err := refreshTokenStore.Create(ctx, user.ID, session.ID, tokenPair.RefreshToken)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(refreshToken.UserID).To(Equal(user.ID))
+		Expect(refreshToken.SessionID).To(Equal(session.ID))
 		Expect(refreshToken.HashedToken).NotTo(BeEmpty())
 		Expect(now.UnixNano()).To(BeNumerically("<", refreshToken.CreatedAt.UnixNano()))
 		Expect(refreshToken.ExpiresAt.UnixMilli()).To(Equal(expiresAt.UnixMilli()))
@@ -60,10 +69,10 @@ var _ = Describe("RefreshTokenStore", Ordered, func() {
 	It("should retrieve a refresh token by user id and token", func() {
 		ctx := context.Background()
 
-		tokenPair, err := jwtManager.GenerateTokenPair(user.ID)
+		tokenPair, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 
-		refreshToken1, err := refreshTokenStore.Create(ctx, user.ID, tokenPair.RefreshToken)
+		refreshToken1, err := refreshTokenStore.Create(ctx, user.ID, session.ID, tokenPair.RefreshToken)
 		Expect(err).NotTo(HaveOccurred())
 
 		refreshToken2, err := refreshTokenStore.ByPrimaryKey(ctx, user.ID, tokenPair.RefreshToken)
@@ -74,13 +83,100 @@ var _ = Describe("RefreshTokenStore", Ordered, func() {
 		Expect(refreshToken2.ExpiresAt).To(Equal(refreshToken1.ExpiresAt))
 
 	})
+	It("should delete a refresh token", func() {
+		ctx := context.Background()
+
+		tokenPair1, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = refreshTokenStore.Create(ctx, user.ID, session.ID, tokenPair1.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		session2, err := sessionStore.Create(ctx, user.ID, "test-agent", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+		tokenPair2, err := jwtManager.GenerateTokenPair(user.ID, session2.ID, time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = refreshTokenStore.Create(ctx, user.ID, session2.ID, tokenPair2.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := refreshTokenStore.Delete(ctx, user.ID, tokenPair1.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+
+		_, err = refreshTokenStore.ByPrimaryKey(ctx, user.ID, tokenPair1.RefreshToken)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		_, err = refreshTokenStore.ByPrimaryKey(ctx, user.ID, tokenPair2.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should rotate a refresh token", func() {
+		ctx := context.Background()
+
+		tokenPair1, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = refreshTokenStore.Create(ctx, user.ID, session.ID, tokenPair1.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		tokenPair2, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		newRefreshToken := tokenPair2.RefreshToken
+
+		refreshToken, err := refreshTokenStore.Rotate(ctx, user.ID, session.ID, tokenPair1.RefreshToken, newRefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refreshToken.SessionID).To(Equal(session.ID))
+		Expect(refreshToken.RotatedAt).To(BeNil())
+
+		rotatedToken, err := refreshTokenStore.ByPrimaryKey(ctx, user.ID, tokenPair1.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotatedToken.RotatedAt).NotTo(BeNil())
+
+		_, err = refreshTokenStore.Rotate(ctx, user.ID, session.ID, tokenPair1.RefreshToken, newRefreshToken)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should rotate a session repeatedly within the same second", func() {
+		ctx := context.Background()
+
+		tokenPair, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = refreshTokenStore.Create(ctx, user.ID, session.ID, tokenPair.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		// Every refresh token carries its own jti, so tokens issued with the
+		// same claims and timestamps still hash differently.
+		for range 3 {
+			newTokenPair, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = refreshTokenStore.Rotate(ctx, user.ID, session.ID, tokenPair.RefreshToken, newTokenPair.RefreshToken)
+			Expect(err).NotTo(HaveOccurred())
+			tokenPair = newTokenPair
+		}
+	})
+
+	It("should delete expired refresh tokens", func() {
+		ctx := context.Background()
+
+		tokenPair, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		refreshToken, err := refreshTokenStore.Create(ctx, user.ID, session.ID, tokenPair.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := refreshTokenStore.DeleteExpired(ctx, refreshToken.ExpiresAt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(0)))
+
+		result, err = refreshTokenStore.DeleteExpired(ctx, refreshToken.ExpiresAt.Add(time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+	})
+
 	It("should delete all user refresh tokens", func() {
 		ctx := context.Background()
 
-		tokenPair, err := jwtManager.GenerateTokenPair(user.ID)
+		tokenPair, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
 		Expect(err).NotTo(HaveOccurred())
 
-		_, err = refreshTokenStore.Create(ctx, user.ID, tokenPair.RefreshToken)
+		_, err = refreshTokenStore.Create(ctx, user.ID, session.ID, tokenPair.RefreshToken)
 		Expect(err).NotTo(HaveOccurred())
 
 		result, err := refreshTokenStore.DeleteUserTokens(ctx, user.ID)
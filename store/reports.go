@@ -3,14 +3,19 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/tracing"
 )
 
+// ReportStore reads skip reports that were deleted with SoftDelete.
 type ReportStore struct {
 	db *sqlx.DB
 }
@@ -21,17 +26,55 @@ func NewReportStore(db *sql.DB) *ReportStore {
 	}
 }
 
-func (s *ReportStore) Create(ctx context.Context, userID uuid.UUID, reportType string) (*dto.Report, error) {
-	const dml = `INSERT INTO reports (user_id, report_type) VALUES ($1, $2) RETURNING *`
+// ReportScope selects the reports visible to a request: the personal reports
+// of UserID, or every report of OrgID when it is set.
+type ReportScope struct {
+	UserID uuid.UUID
+	OrgID  *uuid.UUID
+}
+
+// where returns the condition matching the reports in scope, with its
+// argument bound to placeholder $n.
+func (scope ReportScope) where(n int) (string, any) {
+	if scope.OrgID != nil {
+		return fmt.Sprintf("org_id = $%d", n), *scope.OrgID
+	}
+	return fmt.Sprintf("user_id = $%d AND org_id IS NULL", n), scope.UserID
+}
+
+func (scope ReportScope) String() string {
+	if scope.OrgID != nil {
+		return "organization " + scope.OrgID.String()
+	}
+	return "user " + scope.UserID.String()
+}
+
+// Create creates a report for userID, shared with the organization orgID
+// when it is not nil.
+func (s *ReportStore) Create(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, reportType string) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.Create")
+	defer done(&err)
+
+	const dml = `INSERT INTO reports (user_id, org_id, report_type, trace_parent) VALUES ($1, $2, $3, $4) RETURNING *`
+
+	// Carry the trace context on the row so the worker can continue the
+	// trace that created the report.
+	var traceParent *string
+	if tp := tracing.TraceParent(ctx); tp != "" {
+		traceParent = &tp
+	}
 
 	var report dto.Report
-	if err := s.db.GetContext(ctx, &report, dml, userID, reportType); err != nil {
+	if err := s.db.GetContext(ctx, &report, dml, userID, orgID, reportType, traceParent); err != nil {
 		return nil, fmt.Errorf("failed to insert report for user %s: %w", userID, err)
 	}
 	return &report, nil
 }
 
-func (s *ReportStore) Update(ctx context.Context, report *dto.Report) (*dto.Report, error) {
+func (s *ReportStore) Update(ctx context.Context, report *dto.Report) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.Update")
+	defer done(&err)
+
 	const dml = `UPDATE reports SET 
 	              output_file_path = $1, 
 	              download_url = $2, 
@@ -59,8 +102,11 @@ func (s *ReportStore) Update(ctx context.Context, report *dto.Report) (*dto.Repo
 	return &updatedReport, nil
 }
 
-func (s *ReportStore) ByPrimaryKey(ctx context.Context, userID, reportID uuid.UUID) (*dto.Report, error) {
-	const query = `SELECT * FROM reports WHERE user_id = $1 AND id = $2`
+func (s *ReportStore) ByPrimaryKey(ctx context.Context, userID, reportID uuid.UUID) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.ByPrimaryKey")
+	defer done(&err)
+
+	const query = `SELECT * FROM reports WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL`
 
 	var report dto.Report
 	if err := s.db.GetContext(ctx, &report, query, userID, reportID); err != nil {
@@ -68,3 +114,246 @@ func (s *ReportStore) ByPrimaryKey(ctx context.Context, userID, reportID uuid.UU
 	}
 	return &report, nil
 }
+
+// ByScope returns report reportID if it is in scope.
+func (s *ReportStore) ByScope(ctx context.Context, scope ReportScope, reportID uuid.UUID) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.ByScope")
+	defer done(&err)
+
+	where, arg := scope.where(2)
+	query := `SELECT * FROM reports WHERE id = $1 AND deleted_at IS NULL AND ` + where
+
+	var report dto.Report
+	if err := s.db.GetContext(ctx, &report, query, reportID, arg); err != nil {
+		return nil, fmt.Errorf("failed to get report %s for %s: %w", reportID, scope, err)
+	}
+	return &report, nil
+}
+
+// DeletedByScope returns report reportID in scope if it was deleted after
+// deletedAfter and can still be restored.
+func (s *ReportStore) DeletedByScope(ctx context.Context, scope ReportScope, reportID uuid.UUID, deletedAfter time.Time) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.DeletedByScope")
+	defer done(&err)
+
+	where, arg := scope.where(3)
+	query := `SELECT * FROM reports WHERE id = $1 AND deleted_at > $2 AND ` + where
+
+	var report dto.Report
+	if err := s.db.GetContext(ctx, &report, query, reportID, deletedAfter, arg); err != nil {
+		return nil, fmt.Errorf("failed to get deleted report %s for %s: %w", reportID, scope, err)
+	}
+	return &report, nil
+}
+
+// List returns up to limit reports in scope, newest first.
+func (s *ReportStore) List(ctx context.Context, scope ReportScope, limit int) (_ []dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.List")
+	defer done(&err)
+
+	where, arg := scope.where(1)
+	query := `SELECT * FROM reports WHERE deleted_at IS NULL AND ` + where + ` ORDER BY created_at DESC LIMIT $2`
+
+	reports := []dto.Report{}
+	if err := s.db.SelectContext(ctx, &reports, query, arg, limit); err != nil {
+		return nil, fmt.Errorf("failed to list reports for %s: %w", scope, err)
+	}
+	return reports, nil
+}
+
+// ByUserID returns the reports created by userID, whether personal or shared
+// with an organization, newest first.
+func (s *ReportStore) ByUserID(ctx context.Context, userID uuid.UUID) (_ []dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.ByUserID")
+	defer done(&err)
+
+	const query = `SELECT * FROM reports WHERE user_id = $1 AND deleted_at IS NULL ORDER BY created_at DESC`
+
+	reports := []dto.Report{}
+	if err := s.db.SelectContext(ctx, &reports, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get reports for user %s: %w", userID, err)
+	}
+	return reports, nil
+}
+
+// ByID returns report reportID of any user, for operators.
+func (s *ReportStore) ByID(ctx context.Context, reportID uuid.UUID) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.ByID")
+	defer done(&err)
+
+	const query = `SELECT * FROM reports WHERE id = $1 AND deleted_at IS NULL`
+
+	var report dto.Report
+	if err := s.db.GetContext(ctx, &report, query, reportID); err != nil {
+		return nil, fmt.Errorf("failed to get report %s: %w", reportID, err)
+	}
+	return &report, nil
+}
+
+// ReportFilter narrows down the reports returned by Search, zero fields match
+// every report.
+type ReportFilter struct {
+	UserID     *uuid.UUID
+	OrgID      *uuid.UUID
+	ReportType string
+	Status     string
+	Limit      int
+	Offset     int
+}
+
+var reportStatusConditions = map[string]string{
+	dto.ReportStatusPending:   `started_at IS NULL AND completed_at IS NULL AND failed_at IS NULL`,
+	dto.ReportStatusRunning:   `started_at IS NOT NULL AND completed_at IS NULL AND failed_at IS NULL`,
+	dto.ReportStatusCompleted: `completed_at IS NOT NULL AND failed_at IS NULL`,
+	dto.ReportStatusFailed:    `failed_at IS NOT NULL`,
+}
+
+// Search returns the reports of every user matching filter, newest first.
+func (s *ReportStore) Search(ctx context.Context, filter ReportFilter) (_ []dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.Search")
+	defer done(&err)
+
+	conditions := []string{"deleted_at IS NULL"}
+	args := []any{}
+	bind := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.UserID != nil {
+		bind("user_id = $%d", *filter.UserID)
+	}
+	if filter.OrgID != nil {
+		bind("org_id = $%d", *filter.OrgID)
+	}
+	if filter.ReportType != "" {
+		bind("report_type = $%d", filter.ReportType)
+	}
+	if filter.Status != "" {
+		condition, ok := reportStatusConditions[filter.Status]
+		if !ok {
+			return nil, fmt.Errorf("unknown report status %q", filter.Status)
+		}
+		conditions = append(conditions, condition)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := fmt.Sprintf(`SELECT * FROM reports WHERE %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`,
+		strings.Join(conditions, " AND "), len(args)-1, len(args))
+
+	reports := []dto.Report{}
+	if err := s.db.SelectContext(ctx, &reports, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search reports: %w", err)
+	}
+	return reports, nil
+}
+
+// SoftDelete hides report reportID in scope from every read until it is
+// restored with Restore or purged with Purge.
+func (s *ReportStore) SoftDelete(ctx context.Context, scope ReportScope, reportID uuid.UUID) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.SoftDelete")
+	defer done(&err)
+
+	where, arg := scope.where(2)
+	dml := `UPDATE reports SET deleted_at = CURRENT_TIMESTAMP
+	       WHERE id = $1 AND deleted_at IS NULL AND ` + where + ` RETURNING *`
+
+	var report dto.Report
+	if err := s.db.GetContext(ctx, &report, dml, reportID, arg); err != nil {
+		return nil, fmt.Errorf("failed to delete report %s for %s: %w", reportID, scope, err)
+	}
+	return &report, nil
+}
+
+// Restore undoes the deletion of report reportID in scope if it was deleted
+// after deletedAfter, failing with sql.ErrNoRows otherwise.
+func (s *ReportStore) Restore(ctx context.Context, scope ReportScope, reportID uuid.UUID, deletedAfter time.Time) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.Restore")
+	defer done(&err)
+
+	where, arg := scope.where(3)
+	dml := `UPDATE reports SET deleted_at = NULL
+	       WHERE id = $1 AND deleted_at > $2 AND ` + where + ` RETURNING *`
+
+	var report dto.Report
+	if err := s.db.GetContext(ctx, &report, dml, reportID, deletedAfter, arg); err != nil {
+		return nil, fmt.Errorf("failed to restore report %s for %s: %w", reportID, scope, err)
+	}
+	return &report, nil
+}
+
+// ErrReportNotFinished is returned by Requeue for reports that are still
+// pending or running.
+var ErrReportNotFinished = errors.New("report is not finished")
+
+// Requeue resets the completed or failed report reportID to pending,
+// discarding its outcome, and queues its output file for deletion from blob
+// storage. It fails with ErrReportNotFinished for pending or running reports,
+// which a worker may still be generating.
+func (s *ReportStore) Requeue(ctx context.Context, reportID uuid.UUID) (_ *dto.Report, err error) {
+	ctx, done := instrument(ctx, "ReportStore.Requeue")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var report dto.Report
+	if err := tx.GetContext(ctx, &report, `SELECT * FROM reports WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, reportID); err != nil {
+		return nil, fmt.Errorf("failed to get report %s: %w", reportID, err)
+	}
+	if status := report.Status(); status != dto.ReportStatusCompleted && status != dto.ReportStatusFailed {
+		return nil, fmt.Errorf("failed to requeue %s report %s: %w", status, reportID, ErrReportNotFinished)
+	}
+
+	if report.OutputFilePath != nil {
+		const queueBlob = `INSERT INTO blob_deletions (path) VALUES ($1) ON CONFLICT DO NOTHING`
+		if _, err := tx.ExecContext(ctx, queueBlob, *report.OutputFilePath); err != nil {
+			return nil, fmt.Errorf("failed to queue output of report %s for deletion: %w", reportID, err)
+		}
+	}
+
+	const dml = `UPDATE reports SET
+	              output_file_path = NULL,
+	              download_url = NULL,
+	              download_url_expires_at = NULL,
+	              error_message = NULL,
+	              started_at = NULL,
+	              completed_at = NULL,
+	              failed_at = NULL
+	            WHERE id = $1 RETURNING *`
+	if err := tx.GetContext(ctx, &report, dml, reportID); err != nil {
+		return nil, fmt.Errorf("failed to requeue report %s: %w", reportID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit requeue of report %s: %w", reportID, err)
+	}
+	return &report, nil
+}
+
+// Purge removes the reports deleted before deletedBefore and queues their
+// output files for deletion from blob storage. It returns the number of
+// reports removed.
+func (s *ReportStore) Purge(ctx context.Context, deletedBefore time.Time) (_ int64, err error) {
+	ctx, done := instrument(ctx, "ReportStore.Purge")
+	defer done(&err)
+
+	// Only the outputs of the rows actually deleted are queued, so a report
+	// restored concurrently keeps its output.
+	const dml = `WITH deleted AS (
+	               DELETE FROM reports WHERE deleted_at < $1 RETURNING output_file_path
+	             ), queued AS (
+	               INSERT INTO blob_deletions (path)
+	               SELECT output_file_path FROM deleted WHERE output_file_path IS NOT NULL
+	               ON CONFLICT DO NOTHING
+	             )
+	             SELECT count(*) FROM deleted`
+
+	var purged int64
+	if err := s.db.GetContext(ctx, &purged, dml, deletedBefore); err != nil {
+		return 0, fmt.Errorf("failed to purge deleted reports: %w", err)
+	}
+	return purged, nil
+}
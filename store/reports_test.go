@@ -2,6 +2,7 @@ package store_test
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -9,12 +10,14 @@ import (
 	"github.com/talvor/asyncapi/dto"
 	"github.com/talvor/asyncapi/fixtures"
 	"github.com/talvor/asyncapi/store"
+	"github.com/talvor/asyncapi/tracing"
 )
 
 var _ = Describe("ReportStore", Ordered, func() {
 	var env *fixtures.TestEnv
 	var reportStore *store.ReportStore
 	var userStore *store.UserStore
+	var orgStore *store.OrganizationStore
 
 	BeforeAll(func() {
 		te, err := fixtures.NewTestEnv()
@@ -23,6 +26,7 @@ var _ = Describe("ReportStore", Ordered, func() {
 		env = te
 		reportStore = store.NewReportStore(env.DB)
 		userStore = store.NewUserStore(env.DB)
+		orgStore = store.NewOrganizationStore(env.DB)
 	})
 
 	var user *dto.User
@@ -40,16 +44,26 @@ var _ = Describe("ReportStore", Ordered, func() {
 		ctx := context.Background()
 		now := time.Now()
 
-		report, err := reportStore.Create(ctx, user.ID, "test")
+		report, err := reportStore.Create(ctx, user.ID, nil, "test")
 		Expect(err).NotTo(HaveOccurred())
 		Expect(report.UserID).To(Equal(user.ID))
 		Expect(report.ReportType).To(Equal("test"))
 		Expect(now.UnixNano()).To(BeNumerically("<", report.CreatedAt.UnixNano()))
 	})
 
+	It("should store the trace parent of the creating span", func() {
+		traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		ctx := tracing.WithTraceParent(context.Background(), traceParent)
+
+		report, err := reportStore.Create(ctx, user.ID, nil, "test")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.TraceParent).NotTo(BeNil())
+		Expect(*report.TraceParent).To(ContainSubstring("4bf92f3577b34da6a3ce929d0e0e4736"))
+	})
+
 	It("should update a report", func() {
 		ctx := context.Background()
-		report, err := reportStore.Create(ctx, user.ID, "test")
+		report, err := reportStore.Create(ctx, user.ID, nil, "test")
 		Expect(err).NotTo(HaveOccurred())
 
 		startedAt := report.CreatedAt.Add(time.Minute)
@@ -83,7 +97,7 @@ var _ = Describe("ReportStore", Ordered, func() {
 
 	It("should get a report by user id and report id", func() {
 		ctx := context.Background()
-		report, err := reportStore.Create(ctx, user.ID, "test")
+		report, err := reportStore.Create(ctx, user.ID, nil, "test")
 		Expect(err).NotTo(HaveOccurred())
 
 		report2, err := reportStore.ByPrimaryKey(ctx, user.ID, report.ID)
@@ -91,4 +105,173 @@ var _ = Describe("ReportStore", Ordered, func() {
 		Expect(report2.ID).To(Equal(report.ID))
 		Expect(report2.ReportType).To(Equal(report.ReportType))
 	})
+
+	It("should scope reports to the user or their organization", func() {
+		ctx := context.Background()
+		teammate, err := userStore.CreateUser(ctx, "teammate@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+		org, err := orgStore.Create(ctx, "analytics", user.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		personal, err := reportStore.Create(ctx, user.ID, nil, "test")
+		Expect(err).NotTo(HaveOccurred())
+		shared, err := reportStore.Create(ctx, user.ID, &org.ID, "test")
+		Expect(err).NotTo(HaveOccurred())
+
+		reports, err := reportStore.List(ctx, store.ReportScope{UserID: user.ID}, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(1))
+		Expect(reports[0].ID).To(Equal(personal.ID))
+
+		orgScope := store.ReportScope{UserID: teammate.ID, OrgID: &org.ID}
+		reports, err = reportStore.List(ctx, orgScope, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(1))
+		Expect(reports[0].ID).To(Equal(shared.ID))
+
+		report, err := reportStore.ByScope(ctx, orgScope, shared.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.UserID).To(Equal(user.ID))
+
+		_, err = reportStore.ByScope(ctx, orgScope, personal.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		_, err = reportStore.ByScope(ctx, store.ReportScope{UserID: teammate.ID}, shared.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should search reports of every user", func() {
+		ctx := context.Background()
+		other, err := userStore.CreateUser(ctx, "other@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+
+		pending, err := reportStore.Create(ctx, user.ID, nil, "test")
+		Expect(err).NotTo(HaveOccurred())
+		failed, err := reportStore.Create(ctx, other.ID, nil, "other")
+		Expect(err).NotTo(HaveOccurred())
+		failedAt := time.Now()
+		failed.FailedAt = &failedAt
+		_, err = reportStore.Update(ctx, failed)
+		Expect(err).NotTo(HaveOccurred())
+
+		reports, err := reportStore.Search(ctx, store.ReportFilter{Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(2))
+
+		reports, err = reportStore.Search(ctx, store.ReportFilter{Status: dto.ReportStatusPending, Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(1))
+		Expect(reports[0].ID).To(Equal(pending.ID))
+
+		reports, err = reportStore.Search(ctx, store.ReportFilter{UserID: &other.ID, ReportType: "other", Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(HaveLen(1))
+		Expect(reports[0].Status()).To(Equal(dto.ReportStatusFailed))
+
+		report, err := reportStore.ByID(ctx, failed.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.UserID).To(Equal(other.ID))
+	})
+
+	It("should requeue finished reports and queue their outputs for deletion", func() {
+		ctx := context.Background()
+		report, err := reportStore.Create(ctx, user.ID, nil, "test")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = reportStore.Requeue(ctx, report.ID)
+		Expect(err).To(MatchError(store.ErrReportNotFinished))
+		now := time.Now()
+		report.StartedAt = &now
+		report, err = reportStore.Update(ctx, report)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reportStore.Requeue(ctx, report.ID)
+		Expect(err).To(MatchError(store.ErrReportNotFinished))
+
+		outputFilePath := "users/test/report.csv"
+		report.OutputFilePath = &outputFilePath
+		report.CompletedAt = &now
+		report, err = reportStore.Update(ctx, report)
+		Expect(err).NotTo(HaveOccurred())
+
+		requeued, err := reportStore.Requeue(ctx, report.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requeued.Status()).To(Equal(dto.ReportStatusPending))
+		Expect(requeued.OutputFilePath).To(BeNil())
+
+		paths, err := store.NewBlobDeletionStore(env.DB).Pending(ctx, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(Equal([]string{outputFilePath}))
+	})
+
+	It("should hide deleted reports until they are restored", func() {
+		ctx := context.Background()
+		scope := store.ReportScope{UserID: user.ID}
+		report, err := reportStore.Create(ctx, user.ID, nil, "test")
+		Expect(err).NotTo(HaveOccurred())
+
+		deleted, err := reportStore.SoftDelete(ctx, scope, report.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted.DeletedAt).NotTo(BeNil())
+		_, err = reportStore.SoftDelete(ctx, scope, report.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		_, err = reportStore.ByScope(ctx, scope, report.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		_, err = reportStore.ByID(ctx, report.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		reports, err := reportStore.List(ctx, scope, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(BeEmpty())
+		reports, err = reportStore.Search(ctx, store.ReportFilter{Limit: 10})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reports).To(BeEmpty())
+
+		_, err = reportStore.DeletedByScope(ctx, scope, report.ID, time.Now().Add(time.Minute))
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		found, err := reportStore.DeletedByScope(ctx, scope, report.ID, time.Now().Add(-time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.ID).To(Equal(report.ID))
+
+		_, err = reportStore.Restore(ctx, scope, report.ID, time.Now().Add(time.Minute))
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		restored, err := reportStore.Restore(ctx, scope, report.ID, time.Now().Add(-time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.DeletedAt).To(BeNil())
+
+		_, err = reportStore.ByScope(ctx, scope, report.ID)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reportStore.DeletedByScope(ctx, scope, report.ID, time.Time{})
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should purge reports deleted before the restore period", func() {
+		ctx := context.Background()
+		scope := store.ReportScope{UserID: user.ID}
+		report, err := reportStore.Create(ctx, user.ID, nil, "test")
+		Expect(err).NotTo(HaveOccurred())
+		outputFilePath := "users/test/report.csv"
+		report.OutputFilePath = &outputFilePath
+		_, err = reportStore.Update(ctx, report)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reportStore.SoftDelete(ctx, scope, report.ID)
+		Expect(err).NotTo(HaveOccurred())
+		kept, err := reportStore.Create(ctx, user.ID, nil, "test")
+		Expect(err).NotTo(HaveOccurred())
+
+		purged, err := reportStore.Purge(ctx, time.Now().Add(-time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(purged).To(BeEquivalentTo(0))
+
+		purged, err = reportStore.Purge(ctx, time.Now().Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(purged).To(BeEquivalentTo(1))
+
+		_, err = reportStore.Restore(ctx, scope, report.ID, time.Time{})
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		_, err = reportStore.ByScope(ctx, scope, kept.ID)
+		Expect(err).NotTo(HaveOccurred())
+
+		paths, err := store.NewBlobDeletionStore(env.DB).Pending(ctx, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(Equal([]string{outputFilePath}))
+	})
 })
@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+// RevokedAccessTokenStore is the denylist of access tokens, by jti, that must
+// be rejected before they expire.
+type RevokedAccessTokenStore struct {
+	db *sqlx.DB
+}
+
+func NewRevokedAccessTokenStore(db *sql.DB) *RevokedAccessTokenStore {
+	return &RevokedAccessTokenStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+func (s *RevokedAccessTokenStore) Revoke(ctx context.Context, jti uuid.UUID, expiresAt time.Time) (_ *dto.RevokedAccessToken, err error) {
+	ctx, done := instrument(ctx, "RevokedAccessTokenStore.Revoke")
+	defer done(&err)
+
+	const dml = `INSERT INTO revoked_access_tokens (jti, expires_at) VALUES ($1, $2)
+	            ON CONFLICT (jti) DO UPDATE SET expires_at = EXCLUDED.expires_at RETURNING *`
+
+	var revoked dto.RevokedAccessToken
+	if err := s.db.GetContext(ctx, &revoked, dml, jti, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to revoke access token %s: %w", jti, err)
+	}
+	return &revoked, nil
+}
+
+// RevokeSession revokes the latest access token issued for a session.
+func (s *RevokedAccessTokenStore) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) (_ []dto.RevokedAccessToken, err error) {
+	ctx, done := instrument(ctx, "RevokedAccessTokenStore.RevokeSession")
+	defer done(&err)
+
+	const dml = `INSERT INTO revoked_access_tokens (jti, expires_at)
+	              SELECT access_token_id, access_token_expires_at FROM sessions
+	              WHERE user_id = $1 AND id = $2 AND access_token_id IS NOT NULL AND access_token_expires_at > CURRENT_TIMESTAMP
+	            ON CONFLICT (jti) DO NOTHING RETURNING *`
+
+	var revoked []dto.RevokedAccessToken
+	if err := s.db.SelectContext(ctx, &revoked, dml, userID, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to revoke access token of session %s for user %s: %w", sessionID, userID, err)
+	}
+	return revoked, nil
+}
+
+// RevokeUserSessions revokes the latest access token issued for every session
+// of a user.
+func (s *RevokedAccessTokenStore) RevokeUserSessions(ctx context.Context, userID uuid.UUID) (_ []dto.RevokedAccessToken, err error) {
+	ctx, done := instrument(ctx, "RevokedAccessTokenStore.RevokeUserSessions")
+	defer done(&err)
+
+	const dml = `INSERT INTO revoked_access_tokens (jti, expires_at)
+	              SELECT access_token_id, access_token_expires_at FROM sessions
+	              WHERE user_id = $1 AND access_token_id IS NOT NULL AND access_token_expires_at > CURRENT_TIMESTAMP
+	            ON CONFLICT (jti) DO NOTHING RETURNING *`
+
+	var revoked []dto.RevokedAccessToken
+	if err := s.db.SelectContext(ctx, &revoked, dml, userID); err != nil {
+		return nil, fmt.Errorf("failed to revoke access tokens of user %s: %w", userID, err)
+	}
+	return revoked, nil
+}
+
+// RevokeOtherSessions revokes the latest access token issued for every
+// session of a user but keepSessionID.
+func (s *RevokedAccessTokenStore) RevokeOtherSessions(ctx context.Context, userID, keepSessionID uuid.UUID) (_ []dto.RevokedAccessToken, err error) {
+	ctx, done := instrument(ctx, "RevokedAccessTokenStore.RevokeOtherSessions")
+	defer done(&err)
+
+	const dml = `INSERT INTO revoked_access_tokens (jti, expires_at)
+	              SELECT access_token_id, access_token_expires_at FROM sessions
+	              WHERE user_id = $1 AND id <> $2 AND access_token_id IS NOT NULL AND access_token_expires_at > CURRENT_TIMESTAMP
+	            ON CONFLICT (jti) DO NOTHING RETURNING *`
+
+	var revoked []dto.RevokedAccessToken
+	if err := s.db.SelectContext(ctx, &revoked, dml, userID, keepSessionID); err != nil {
+		return nil, fmt.Errorf("failed to revoke other access tokens of user %s: %w", userID, err)
+	}
+	return revoked, nil
+}
+
+func (s *RevokedAccessTokenStore) ByJTI(ctx context.Context, jti uuid.UUID) (_ *dto.RevokedAccessToken, err error) {
+	ctx, done := instrument(ctx, "RevokedAccessTokenStore.ByJTI")
+	defer done(&err)
+
+	const query = `SELECT * FROM revoked_access_tokens WHERE jti = $1`
+
+	var revoked dto.RevokedAccessToken
+	if err := s.db.GetContext(ctx, &revoked, query, jti); err != nil {
+		return nil, fmt.Errorf("failed to get revoked access token %s: %w", jti, err)
+	}
+	return &revoked, nil
+}
+
+// DeleteExpired removes tokens that expired before before from the denylist;
+// they are rejected on their expiry anyway.
+func (s *RevokedAccessTokenStore) DeleteExpired(ctx context.Context, before time.Time) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "RevokedAccessTokenStore.DeleteExpired")
+	defer done(&err)
+
+	const dml = `DELETE FROM revoked_access_tokens WHERE expires_at < $1`
+
+	result, err := s.db.ExecContext(ctx, dml, before)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete expired revoked access tokens: %w", err)
+	}
+	return result, nil
+}
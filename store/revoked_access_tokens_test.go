@@ -0,0 +1,122 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("RevokedAccessTokenStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var revokedStore *store.RevokedAccessTokenStore
+	var sessionStore *store.SessionStore
+	var userStore *store.UserStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		revokedStore = store.NewRevokedAccessTokenStore(env.DB)
+		sessionStore = store.NewSessionStore(env.DB)
+		userStore = store.NewUserStore(env.DB)
+	})
+
+	var user *dto.User
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+
+		ctx := context.Background()
+		user, err = userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should revoke an access token", func() {
+		ctx := context.Background()
+		jti := uuid.New()
+
+		_, err := revokedStore.Revoke(ctx, jti, time.Now().Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+
+		revoked, err := revokedStore.ByJTI(ctx, jti)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revoked.JTI).To(Equal(jti))
+
+		_, err = revokedStore.ByJTI(ctx, uuid.New())
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should revoke the latest access token of every session of a user", func() {
+		ctx := context.Background()
+		expiresAt := time.Now().Add(time.Minute)
+
+		var jtis []uuid.UUID
+		for range 2 {
+			session, err := sessionStore.Create(ctx, user.ID, "test-agent", "127.0.0.1")
+			Expect(err).NotTo(HaveOccurred())
+			jti := uuid.New()
+			_, err = sessionStore.SetAccessToken(ctx, session.ID, jti, expiresAt)
+			Expect(err).NotTo(HaveOccurred())
+			jtis = append(jtis, jti)
+		}
+
+		revoked, err := revokedStore.RevokeUserSessions(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revoked).To(HaveLen(2))
+
+		for _, jti := range jtis {
+			_, err := revokedStore.ByJTI(ctx, jti)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should revoke the access token a session replaces", func() {
+		ctx := context.Background()
+		expiresAt := time.Now().Add(time.Minute)
+		session, err := sessionStore.Create(ctx, user.ID, "test-agent", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+
+		revoked, err := revokedStore.RevokeSession(ctx, user.ID, session.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revoked).To(BeEmpty())
+
+		oldJTI := uuid.New()
+		_, err = sessionStore.SetAccessToken(ctx, session.ID, oldJTI, expiresAt)
+		Expect(err).NotTo(HaveOccurred())
+
+		// A refresh revokes the previous access token before recording the
+		// new one.
+		revoked, err = revokedStore.RevokeSession(ctx, user.ID, session.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revoked).To(HaveLen(1))
+		Expect(revoked[0].JTI).To(Equal(oldJTI))
+
+		newJTI := uuid.New()
+		_, err = sessionStore.SetAccessToken(ctx, session.ID, newJTI, expiresAt)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = revokedStore.ByJTI(ctx, newJTI)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should delete expired access tokens", func() {
+		ctx := context.Background()
+
+		_, err := revokedStore.Revoke(ctx, uuid.New(), time.Now().Add(-time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = revokedStore.Revoke(ctx, uuid.New(), time.Now().Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := revokedStore.DeleteExpired(ctx, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+	})
+})
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type RoleStore struct {
+	db *sqlx.DB
+}
+
+func NewRoleStore(db *sql.DB) *RoleStore {
+	return &RoleStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+func (s *RoleStore) ByName(ctx context.Context, name string) (_ *dto.Role, err error) {
+	ctx, done := instrument(ctx, "RoleStore.ByName")
+	defer done(&err)
+
+	const query = `SELECT * FROM roles WHERE name = $1`
+
+	var role dto.Role
+	if err := s.db.GetContext(ctx, &role, query, name); err != nil {
+		return nil, fmt.Errorf("failed to get role %s: %w", name, err)
+	}
+	return &role, nil
+}
+
+func (s *RoleStore) Assign(ctx context.Context, userID uuid.UUID, role string) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "RoleStore.Assign")
+	defer done(&err)
+
+	const dml = `INSERT INTO user_roles (user_id, role) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	result, err := s.db.ExecContext(ctx, dml, userID, role)
+	if err != nil {
+		return result, fmt.Errorf("failed to assign role %s to user %s: %w", role, userID, err)
+	}
+	return result, nil
+}
+
+func (s *RoleStore) Unassign(ctx context.Context, userID uuid.UUID, role string) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "RoleStore.Unassign")
+	defer done(&err)
+
+	const dml = `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`
+
+	result, err := s.db.ExecContext(ctx, dml, userID, role)
+	if err != nil {
+		return result, fmt.Errorf("failed to unassign role %s from user %s: %w", role, userID, err)
+	}
+	return result, nil
+}
+
+func (s *RoleStore) RolesByUserID(ctx context.Context, userID uuid.UUID) (_ []string, err error) {
+	ctx, done := instrument(ctx, "RoleStore.RolesByUserID")
+	defer done(&err)
+
+	const query = `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`
+
+	roles := []string{}
+	if err := s.db.SelectContext(ctx, &roles, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get roles for user %s: %w", userID, err)
+	}
+	return roles, nil
+}
+
+// PermissionsByUserID returns the permissions granted to a user by all their
+// roles.
+func (s *RoleStore) PermissionsByUserID(ctx context.Context, userID uuid.UUID) (_ []string, err error) {
+	ctx, done := instrument(ctx, "RoleStore.PermissionsByUserID")
+	defer done(&err)
+
+	const query = `SELECT DISTINCT rp.permission FROM user_roles ur
+	              JOIN role_permissions rp ON rp.role = ur.role
+	              WHERE ur.user_id = $1 ORDER BY rp.permission`
+
+	permissions := []string{}
+	if err := s.db.SelectContext(ctx, &permissions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get permissions for user %s: %w", userID, err)
+	}
+	return permissions, nil
+}
@@ -0,0 +1,79 @@
+package store_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("RoleStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var roleStore *store.RoleStore
+	var userStore *store.UserStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		roleStore = store.NewRoleStore(env.DB)
+		userStore = store.NewUserStore(env.DB)
+	})
+
+	var user *dto.User
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+
+		ctx := context.Background()
+		user, err = userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should seed the admin role", func() {
+		role, err := roleStore.ByName(context.Background(), dto.RoleAdmin)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(role.Name).To(Equal(dto.RoleAdmin))
+	})
+
+	It("should grant permissions through assigned roles", func() {
+		ctx := context.Background()
+
+		permissions, err := roleStore.PermissionsByUserID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(permissions).To(BeEmpty())
+
+		_, err = roleStore.Assign(ctx, user.ID, dto.RoleAdmin)
+		Expect(err).NotTo(HaveOccurred())
+		// Assigning twice is a no-op.
+		result, err := roleStore.Assign(ctx, user.ID, dto.RoleAdmin)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(0)))
+
+		roles, err := roleStore.RolesByUserID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(roles).To(Equal([]string{dto.RoleAdmin}))
+
+		permissions, err = roleStore.PermissionsByUserID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(permissions).To(ContainElements(dto.PermissionUsersRead, dto.PermissionUsersWrite))
+
+		result, err = roleStore.Unassign(ctx, user.ID, dto.RoleAdmin)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+
+		permissions, err = roleStore.PermissionsByUserID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(permissions).To(BeEmpty())
+	})
+
+	It("should not assign unknown roles", func() {
+		_, err := roleStore.Assign(context.Background(), user.ID, "superuser")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type SessionStore struct {
+	db *sqlx.DB
+}
+
+func NewSessionStore(db *sql.DB) *SessionStore {
+	return &SessionStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+func (s *SessionStore) Create(ctx context.Context, userID uuid.UUID, userAgent, ipAddress string) (_ *dto.Session, err error) {
+	ctx, done := instrument(ctx, "SessionStore.Create")
+	defer done(&err)
+
+	const dml = `INSERT INTO sessions (user_id, user_agent, ip_address) VALUES ($1, $2, $3) RETURNING *`
+
+	var session dto.Session
+	if err := s.db.GetContext(ctx, &session, dml, userID, userAgent, ipAddress); err != nil {
+		return nil, fmt.Errorf("failed to insert session for user %s: %w", userID, err)
+	}
+	return &session, nil
+}
+
+// Touch records that the session was used from userAgent and ipAddress.
+func (s *SessionStore) Touch(ctx context.Context, sessionID uuid.UUID, userAgent, ipAddress string) (_ *dto.Session, err error) {
+	ctx, done := instrument(ctx, "SessionStore.Touch")
+	defer done(&err)
+
+	const dml = `UPDATE sessions SET
+	              user_agent = $1,
+	              ip_address = $2,
+	              last_used_at = CURRENT_TIMESTAMP
+	            WHERE id = $3 RETURNING *`
+
+	var session dto.Session
+	if err := s.db.GetContext(ctx, &session, dml, userAgent, ipAddress, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to touch session %s: %w", sessionID, err)
+	}
+	return &session, nil
+}
+
+// SetAccessToken records the latest access token issued for a session.
+func (s *SessionStore) SetAccessToken(ctx context.Context, sessionID, jti uuid.UUID, expiresAt time.Time) (_ *dto.Session, err error) {
+	ctx, done := instrument(ctx, "SessionStore.SetAccessToken")
+	defer done(&err)
+
+	const dml = `UPDATE sessions SET
+	              access_token_id = $1,
+	              access_token_expires_at = $2
+	            WHERE id = $3 RETURNING *`
+
+	var session dto.Session
+	if err := s.db.GetContext(ctx, &session, dml, jti, expiresAt, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to set access token of session %s: %w", sessionID, err)
+	}
+	return &session, nil
+}
+
+func (s *SessionStore) ByPrimaryKey(ctx context.Context, userID, sessionID uuid.UUID) (_ *dto.Session, err error) {
+	ctx, done := instrument(ctx, "SessionStore.ByPrimaryKey")
+	defer done(&err)
+
+	const query = `SELECT * FROM sessions WHERE user_id = $1 AND id = $2`
+
+	var session dto.Session
+	if err := s.db.GetContext(ctx, &session, query, userID, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to get session %s for user %s: %w", sessionID, userID, err)
+	}
+	return &session, nil
+}
+
+func (s *SessionStore) ByUserID(ctx context.Context, userID uuid.UUID) (_ []dto.Session, err error) {
+	ctx, done := instrument(ctx, "SessionStore.ByUserID")
+	defer done(&err)
+
+	const query = `SELECT * FROM sessions WHERE user_id = $1 ORDER BY last_used_at DESC`
+
+	var sessions []dto.Session
+	if err := s.db.SelectContext(ctx, &sessions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get sessions for user %s: %w", userID, err)
+	}
+	return sessions, nil
+}
+
+// Delete deletes a session and, by cascade, its refresh tokens.
+func (s *SessionStore) Delete(ctx context.Context, userID, sessionID uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "SessionStore.Delete")
+	defer done(&err)
+
+	const dml = `DELETE FROM sessions WHERE user_id = $1 AND id = $2`
+
+	result, err := s.db.ExecContext(ctx, dml, userID, sessionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete session %s for user %s: %w", sessionID, userID, err)
+	}
+	return result, nil
+}
+
+// DeleteUserSessions deletes every session of a user and, by cascade, their
+// refresh tokens.
+func (s *SessionStore) DeleteUserSessions(ctx context.Context, userID uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "SessionStore.DeleteUserSessions")
+	defer done(&err)
+
+	const dml = `DELETE FROM sessions WHERE user_id = $1`
+
+	result, err := s.db.ExecContext(ctx, dml, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete sessions for user %s: %w", userID, err)
+	}
+	return result, nil
+}
+
+// DeleteOtherSessions deletes every session of a user but keepSessionID and,
+// by cascade, their refresh tokens.
+func (s *SessionStore) DeleteOtherSessions(ctx context.Context, userID, keepSessionID uuid.UUID) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "SessionStore.DeleteOtherSessions")
+	defer done(&err)
+
+	const dml = `DELETE FROM sessions WHERE user_id = $1 AND id <> $2`
+
+	result, err := s.db.ExecContext(ctx, dml, userID, keepSessionID)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete other sessions for user %s: %w", userID, err)
+	}
+	return result, nil
+}
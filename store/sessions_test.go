@@ -0,0 +1,137 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/apiserver"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("SessionStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var sessionStore *store.SessionStore
+	var refreshTokenStore *store.RefreshTokenStore
+	var userStore *store.UserStore
+	var jwtManager *apiserver.JwtManager
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		sessionStore = store.NewSessionStore(env.DB)
+		refreshTokenStore = store.NewRefreshTokenStore(env.DB)
+		userStore = store.NewUserStore(env.DB)
+		jwtManager, err = apiserver.NewJwtManager(env.Config)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	var user *dto.User
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+
+		ctx := context.Background()
+		user, err = userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should create a session", func() {
+		ctx := context.Background()
+		now := time.Now()
+
+		session, err := sessionStore.Create(ctx, user.ID, "test-agent", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(session.ID).NotTo(BeZero())
+		Expect(session.UserID).To(Equal(user.ID))
+		Expect(session.UserAgent).To(Equal("test-agent"))
+		Expect(session.IPAddress).To(Equal("127.0.0.1"))
+		Expect(now.UnixNano()).To(BeNumerically("<", session.CreatedAt.UnixNano()))
+	})
+
+	It("should touch a session", func() {
+		ctx := context.Background()
+
+		session, err := sessionStore.Create(ctx, user.ID, "test-agent", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+
+		touched, err := sessionStore.Touch(ctx, session.ID, "other-agent", "10.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(touched.UserAgent).To(Equal("other-agent"))
+		Expect(touched.IPAddress).To(Equal("10.0.0.1"))
+		Expect(touched.LastUsedAt.UnixNano()).To(BeNumerically(">", session.LastUsedAt.UnixNano()))
+	})
+
+	It("should list the sessions of a user", func() {
+		ctx := context.Background()
+
+		_, err := sessionStore.Create(ctx, user.ID, "laptop", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = sessionStore.Create(ctx, user.ID, "phone", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+
+		sessions, err := sessionStore.ByUserID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions).To(HaveLen(2))
+	})
+
+	It("should delete a session and its refresh tokens", func() {
+		ctx := context.Background()
+
+		session, err := sessionStore.Create(ctx, user.ID, "test-agent", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+
+		tokenPair, err := jwtManager.GenerateTokenPair(user.ID, session.ID, time.Time{}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = refreshTokenStore.Create(ctx, user.ID, session.ID, tokenPair.RefreshToken)
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := sessionStore.Delete(ctx, user.ID, session.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+
+		_, err = sessionStore.ByPrimaryKey(ctx, user.ID, session.ID)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		_, err = refreshTokenStore.ByPrimaryKey(ctx, user.ID, tokenPair.RefreshToken)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should delete all sessions of a user", func() {
+		ctx := context.Background()
+
+		for range 2 {
+			_, err := sessionStore.Create(ctx, user.ID, "test-agent", "127.0.0.1")
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		result, err := sessionStore.DeleteUserSessions(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(2)))
+	})
+
+	It("should delete all sessions of a user but one", func() {
+		ctx := context.Background()
+
+		current, err := sessionStore.Create(ctx, user.ID, "laptop", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = sessionStore.Create(ctx, user.ID, "phone", "127.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := sessionStore.DeleteOtherSessions(ctx, user.ID, current.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+
+		sessions, err := sessionStore.ByUserID(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions).To(HaveLen(1))
+		Expect(sessions[0].ID).To(Equal(current.ID))
+	})
+})
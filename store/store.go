@@ -4,14 +4,36 @@ import "database/sql"
 
 type Store struct {
 	Users         *UserStore
+	UserTokens    *UserTokenStore
+	RecoveryCodes *RecoveryCodeStore
+	Roles         *RoleStore
 	RefreshTokens *RefreshTokenStore
+	Sessions      *SessionStore
+	APIKeys       *APIKeyStore
+	RevokedTokens *RevokedAccessTokenStore
+	Organizations *OrganizationStore
 	Reports       *ReportStore
+	RateLimits    *RateLimitStore
+	AuditEvents   *AuditEventStore
+	InviteCodes   *InviteCodeStore
+	BlobDeletions *BlobDeletionStore
 }
 
 func New(db *sql.DB) *Store {
 	return &Store{
 		Users:         NewUserStore(db),
+		UserTokens:    NewUserTokenStore(db),
+		RecoveryCodes: NewRecoveryCodeStore(db),
+		Roles:         NewRoleStore(db),
 		RefreshTokens: NewRefreshTokenStore(db),
+		Sessions:      NewSessionStore(db),
+		APIKeys:       NewAPIKeyStore(db),
+		RevokedTokens: NewRevokedAccessTokenStore(db),
+		Organizations: NewOrganizationStore(db),
 		Reports:       NewReportStore(db),
+		RateLimits:    NewRateLimitStore(db),
+		AuditEvents:   NewAuditEventStore(db),
+		InviteCodes:   NewInviteCodeStore(db),
+		BlobDeletions: NewBlobDeletionStore(db),
 	}
 }
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/talvor/asyncapi/dto"
+)
+
+type UserTokenStore struct {
+	db *sqlx.DB
+}
+
+func NewUserTokenStore(db *sql.DB) *UserTokenStore {
+	return &UserTokenStore{
+		db: sqlx.NewDb(db, "postgres"),
+	}
+}
+
+// Create stores the hash of token, to be used once for purpose before
+// expiresAt.
+func (s *UserTokenStore) Create(ctx context.Context, userID uuid.UUID, purpose, token string, expiresAt time.Time) (_ *dto.UserToken, err error) {
+	ctx, done := instrument(ctx, "UserTokenStore.Create")
+	defer done(&err)
+
+	const dml = `INSERT INTO user_tokens (hashed_token, user_id, purpose, expires_at) VALUES ($1, $2, $3, $4) RETURNING *`
+
+	var userToken dto.UserToken
+	if err := s.db.GetContext(ctx, &userToken, dml, dto.HashSecretToken(token), userID, purpose, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to insert %s token: %w", purpose, err)
+	}
+	return &userToken, nil
+}
+
+// Consume deletes token and returns it if it was issued for purpose and has
+// not expired. It fails with sql.ErrNoRows otherwise, so each token can be
+// used once.
+func (s *UserTokenStore) Consume(ctx context.Context, purpose, token string) (_ *dto.UserToken, err error) {
+	ctx, done := instrument(ctx, "UserTokenStore.Consume")
+	defer done(&err)
+
+	const dml = `DELETE FROM user_tokens
+	            WHERE hashed_token = $1 AND purpose = $2 AND expires_at > CURRENT_TIMESTAMP RETURNING *`
+
+	var userToken dto.UserToken
+	if err := s.db.GetContext(ctx, &userToken, dml, dto.HashSecretToken(token), purpose); err != nil {
+		return nil, fmt.Errorf("failed to consume %s token: %w", purpose, err)
+	}
+	return &userToken, nil
+}
+
+// DeleteUserTokens invalidates the outstanding tokens of a user for purpose.
+func (s *UserTokenStore) DeleteUserTokens(ctx context.Context, userID uuid.UUID, purpose string) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "UserTokenStore.DeleteUserTokens")
+	defer done(&err)
+
+	const dml = `DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`
+
+	result, err := s.db.ExecContext(ctx, dml, userID, purpose)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete %s tokens for user %s: %w", purpose, userID, err)
+	}
+	return result, nil
+}
+
+func (s *UserTokenStore) DeleteExpired(ctx context.Context, before time.Time) (_ sql.Result, err error) {
+	ctx, done := instrument(ctx, "UserTokenStore.DeleteExpired")
+	defer done(&err)
+
+	const dml = `DELETE FROM user_tokens WHERE expires_at < $1`
+
+	result, err := s.db.ExecContext(ctx, dml, before)
+	if err != nil {
+		return result, fmt.Errorf("failed to delete expired user tokens: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,83 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/dto"
+	"github.com/talvor/asyncapi/fixtures"
+	"github.com/talvor/asyncapi/store"
+)
+
+var _ = Describe("UserTokenStore", Ordered, func() {
+	var env *fixtures.TestEnv
+	var userTokenStore *store.UserTokenStore
+	var userStore *store.UserStore
+
+	BeforeAll(func() {
+		te, err := fixtures.NewTestEnv()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(te.ContainerCleanup)
+		env = te
+		userTokenStore = store.NewUserTokenStore(env.DB)
+		userStore = store.NewUserStore(env.DB)
+	})
+
+	var user *dto.User
+	BeforeEach(func() {
+		err := env.SetupDB()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(env.TeardownDB)
+
+		ctx := context.Background()
+		user, err = userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should consume a token once", func() {
+		ctx := context.Background()
+		token, err := dto.NewSecretToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		userToken, err := userTokenStore.Create(ctx, user.ID, dto.UserTokenEmailVerification, token, time.Now().Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(userToken.HashedToken).NotTo(Equal(token))
+
+		consumed, err := userTokenStore.Consume(ctx, dto.UserTokenEmailVerification, token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(consumed.UserID).To(Equal(user.ID))
+
+		_, err = userTokenStore.Consume(ctx, dto.UserTokenEmailVerification, token)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+	})
+
+	It("should not consume an expired token or one for another purpose", func() {
+		ctx := context.Background()
+		token, err := dto.NewSecretToken()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = userTokenStore.Create(ctx, user.ID, dto.UserTokenEmailVerification, token, time.Now().Add(-time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = userTokenStore.Consume(ctx, dto.UserTokenEmailVerification, token)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		_, err = userTokenStore.Consume(ctx, "other", token)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		result, err := userTokenStore.DeleteExpired(ctx, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RowsAffected()).To(Equal(int64(1)))
+	})
+
+	It("should mark a user's email verified", func() {
+		ctx := context.Background()
+		Expect(user.IsEmailVerified()).To(BeFalse())
+
+		verified, err := userStore.MarkEmailVerified(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(verified.IsEmailVerified()).To(BeTrue())
+	})
+})
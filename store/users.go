@@ -3,7 +3,10 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
@@ -21,7 +24,10 @@ func NewUserStore(db *sql.DB) *UserStore {
 	}
 }
 
-func (s *UserStore) CreateUser(ctx context.Context, email, password string) (*dto.User, error) {
+func (s *UserStore) CreateUser(ctx context.Context, email, password string) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.CreateUser")
+	defer done(&err)
+
 	const dml = `INSERT INTO users (email, hashed_password) VALUES ($1, $2) RETURNING *`
 
 	var user dto.User
@@ -36,8 +42,12 @@ func (s *UserStore) CreateUser(ctx context.Context, email, password string) (*dt
 	return &user, nil
 }
 
-func (s *UserStore) ByEmail(ctx context.Context, email string) (*dto.User, error) {
-	const query = `SELECT * FROM users WHERE email = $1`
+// ByEmail returns the user whose email equals email, ignoring case.
+func (s *UserStore) ByEmail(ctx context.Context, email string) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.ByEmail")
+	defer done(&err)
+
+	const query = `SELECT * FROM users WHERE lower(email) = lower($1)`
 
 	var user dto.User
 	if err := s.db.GetContext(ctx, &user, query, email); err != nil {
@@ -46,7 +56,10 @@ func (s *UserStore) ByEmail(ctx context.Context, email string) (*dto.User, error
 	return &user, nil
 }
 
-func (s *UserStore) ByID(ctx context.Context, userID uuid.UUID) (*dto.User, error) {
+func (s *UserStore) ByID(ctx context.Context, userID uuid.UUID) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.ByID")
+	defer done(&err)
+
 	const query = `SELECT * FROM users WHERE id = $1`
 
 	var user dto.User
@@ -55,3 +68,294 @@ func (s *UserStore) ByID(ctx context.Context, userID uuid.UUID) (*dto.User, erro
 	}
 	return &user, nil
 }
+
+// ErrUserLocked is returned when claiming a sign-in attempt for a user who is
+// locked.
+var ErrUserLocked = errors.New("user is locked")
+
+// SigninLockout decides until when a user with failedAttempts consecutive
+// failed sign-ins, the latest at now, is locked.
+type SigninLockout interface {
+	LockedUntil(failedAttempts int, now time.Time) time.Time
+}
+
+// ClaimSigninAttempt counts a sign-in attempt of userID as failed before its
+// password or code is checked, and locks the user as lockout decides. It fails
+// with ErrUserLocked if the user is locked at now. Concurrent attempts are
+// serialized, so a burst of them cannot get past the lockout. A successful
+// attempt is cleared with ResetFailedSignins.
+func (s *UserStore) ClaimSigninAttempt(ctx context.Context, userID uuid.UUID, lockout SigninLockout, now time.Time) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.ClaimSigninAttempt")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var user dto.User
+	if err := tx.GetContext(ctx, &user, `SELECT * FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", userID, err)
+	}
+	if user.IsLocked(now) {
+		return nil, fmt.Errorf("user %s is locked until %s: %w", userID, user.LockedUntil, ErrUserLocked)
+	}
+
+	const dml = `UPDATE users SET
+	              failed_signin_attempts = $1,
+	              last_failed_signin_at = CURRENT_TIMESTAMP,
+	              locked_until = $2
+	            WHERE id = $3 RETURNING *`
+	failedAttempts := user.FailedSigninAttempts + 1
+	if err := tx.GetContext(ctx, &user, dml, failedAttempts, lockout.LockedUntil(failedAttempts, now), userID); err != nil {
+		return nil, fmt.Errorf("failed to record signin attempt for user %s: %w", userID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit signin attempt for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+// ResetFailedSignins clears the failed sign-in attempts of a user, unlocking
+// the account.
+func (s *UserStore) ResetFailedSignins(ctx context.Context, userID uuid.UUID) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.ResetFailedSignins")
+	defer done(&err)
+
+	const dml = `UPDATE users SET
+	              failed_signin_attempts = 0,
+	              last_failed_signin_at = NULL,
+	              locked_until = NULL
+	            WHERE id = $1 RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, userID); err != nil {
+		return nil, fmt.Errorf("failed to reset failed signins for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+// Search returns up to limit users, skipping offset, whose email or name
+// contains query, newest first. An empty query matches every user.
+func (s *UserStore) Search(ctx context.Context, query string, limit, offset int) (_ []dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.Search")
+	defer done(&err)
+
+	const sqlQuery = `SELECT * FROM users
+	                 WHERE $1 = '' OR email ILIKE '%' || $1 || '%' OR name ILIKE '%' || $1 || '%'
+	                 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query)
+
+	users := []dto.User{}
+	if err := s.db.SelectContext(ctx, &users, sqlQuery, escaped, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, nil
+}
+
+// Disable prevents a user from signing in or using existing credentials. A
+// disabled user keeps the time they were first disabled at.
+func (s *UserStore) Disable(ctx context.Context, userID uuid.UUID) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.Disable")
+	defer done(&err)
+
+	const dml = `UPDATE users SET disabled_at = COALESCE(disabled_at, CURRENT_TIMESTAMP) WHERE id = $1 RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, userID); err != nil {
+		return nil, fmt.Errorf("failed to disable user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+func (s *UserStore) Enable(ctx context.Context, userID uuid.UUID) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.Enable")
+	defer done(&err)
+
+	const dml = `UPDATE users SET disabled_at = NULL WHERE id = $1 RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, userID); err != nil {
+		return nil, fmt.Errorf("failed to enable user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+func (s *UserStore) MarkEmailVerified(ctx context.Context, userID uuid.UUID) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.MarkEmailVerified")
+	defer done(&err)
+
+	const dml = `UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP)
+	            WHERE id = $1 RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, userID); err != nil {
+		return nil, fmt.Errorf("failed to mark email verified for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+func (s *UserStore) UpdatePassword(ctx context.Context, userID uuid.UUID, password string) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.UpdatePassword")
+	defer done(&err)
+
+	const dml = `UPDATE users SET hashed_password = $1 WHERE id = $2 RETURNING *`
+
+	hashedPassword, err := dto.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hashing password: %w", err)
+	}
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, hashedPassword, userID); err != nil {
+		return nil, fmt.Errorf("failed to update password for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+func (s *UserStore) UpdateProfile(ctx context.Context, userID uuid.UUID, name string) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.UpdateProfile")
+	defer done(&err)
+
+	const dml = `UPDATE users SET name = $1 WHERE id = $2 RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, name, userID); err != nil {
+		return nil, fmt.Errorf("failed to update profile for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+// SetPendingTOTPSecret stores a TOTP secret to be confirmed by EnableTOTP. It
+// fails with sql.ErrNoRows when TOTP is already enabled.
+func (s *UserStore) SetPendingTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.SetPendingTOTPSecret")
+	defer done(&err)
+
+	const dml = `UPDATE users SET totp_secret = $1, totp_last_step = NULL
+	            WHERE id = $2 AND totp_enabled_at IS NULL RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, secret, userID); err != nil {
+		return nil, fmt.Errorf("failed to set totp secret for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+// EnableTOTP enforces the pending TOTP secret, step being the time step of the
+// code that confirmed it.
+func (s *UserStore) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.EnableTOTP")
+	defer done(&err)
+
+	const dml = `UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $1
+	            WHERE id = $2 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, step, userID); err != nil {
+		return nil, fmt.Errorf("failed to enable totp for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+// UseTOTPStep records that a code of time step was accepted. It fails with
+// sql.ErrNoRows when a code of the same or a later step was accepted before,
+// which means the code is being replayed.
+func (s *UserStore) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.UseTOTPStep")
+	defer done(&err)
+
+	const dml = `UPDATE users SET totp_last_step = $1
+	            WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1) RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, step, userID); err != nil {
+		return nil, fmt.Errorf("failed to use totp step for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+func (s *UserStore) DisableTOTP(ctx context.Context, userID uuid.UUID) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.DisableTOTP")
+	defer done(&err)
+
+	const dml = `UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+	            WHERE id = $1 RETURNING *`
+
+	var user dto.User
+	if err := s.db.GetContext(ctx, &user, dml, userID); err != nil {
+		return nil, fmt.Errorf("failed to disable totp for user %s: %w", userID, err)
+	}
+	return &user, nil
+}
+
+// ErrLastAdmin is returned by Delete when the user is the only admin of an
+// organization that has other members.
+var ErrLastAdmin = errors.New("last admin of an organization")
+
+// Delete deletes a user together with everything they own: their sessions,
+// tokens, memberships and reports, as well as the organizations they were the
+// only member of. The output files of their reports are queued for deletion
+// from blob storage. Audit events about the user are kept. It fails with
+// ErrLastAdmin when the user is the only admin of an organization that has
+// other members.
+func (s *UserStore) Delete(ctx context.Context, userID uuid.UUID) (_ *dto.User, err error) {
+	ctx, done := instrument(ctx, "UserStore.Delete")
+	defer done(&err)
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the memberships of the user's organizations so no other admin can
+	// be demoted or removed until the user is gone.
+	const lockMemberships = `SELECT 1 FROM memberships
+	                        WHERE org_id IN (SELECT org_id FROM memberships WHERE user_id = $1)
+	                        FOR UPDATE`
+	if _, err := tx.ExecContext(ctx, lockMemberships, userID); err != nil {
+		return nil, fmt.Errorf("failed to lock memberships of user %s: %w", userID, err)
+	}
+
+	const lastAdmin = `SELECT EXISTS (
+	                     SELECT 1 FROM memberships m
+	                     WHERE m.user_id = $1 AND m.role = 'admin'
+	                     AND EXISTS (SELECT 1 FROM memberships WHERE org_id = m.org_id AND user_id <> $1)
+	                     AND NOT EXISTS (SELECT 1 FROM memberships WHERE org_id = m.org_id AND user_id <> $1 AND role = 'admin')
+	                   )`
+	var isLastAdmin bool
+	if err := tx.GetContext(ctx, &isLastAdmin, lastAdmin, userID); err != nil {
+		return nil, fmt.Errorf("failed to check organizations of user %s: %w", userID, err)
+	}
+	if isLastAdmin {
+		return nil, fmt.Errorf("failed to delete user %s: %w", userID, ErrLastAdmin)
+	}
+
+	const queueBlobs = `INSERT INTO blob_deletions (path)
+	                   SELECT output_file_path FROM reports WHERE user_id = $1 AND output_file_path IS NOT NULL
+	                   ON CONFLICT DO NOTHING`
+	if _, err := tx.ExecContext(ctx, queueBlobs, userID); err != nil {
+		return nil, fmt.Errorf("failed to queue report outputs of user %s for deletion: %w", userID, err)
+	}
+
+	const deleteOrgs = `DELETE FROM organizations o
+	                   WHERE EXISTS (SELECT 1 FROM memberships WHERE org_id = o.id AND user_id = $1)
+	                   AND NOT EXISTS (SELECT 1 FROM memberships WHERE org_id = o.id AND user_id <> $1)`
+	if _, err := tx.ExecContext(ctx, deleteOrgs, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete organizations of user %s: %w", userID, err)
+	}
+
+	var user dto.User
+	if err := tx.GetContext(ctx, &user, `DELETE FROM users WHERE id = $1 RETURNING *`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete user %s: %w", userID, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit deletion of user %s: %w", userID, err)
+	}
+	return &user, nil
+}
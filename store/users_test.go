@@ -2,8 +2,11 @@ package store_test
 
 import (
 	"context"
+	"database/sql"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/talvor/asyncapi/fixtures"
@@ -49,7 +52,7 @@ var _ = Describe("UserStore", Ordered, func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(user2.ID).To(Equal(user1.ID))
 		Expect(user2.Email).To(Equal(user1.Email))
-		Expect(user2.HashedPasswordBase64).To(Equal(user1.HashedPasswordBase64))
+		Expect(user2.HashedPassword).To(Equal(user1.HashedPassword))
 	})
 
 	It("should retrieve a user by Email", func() {
@@ -62,7 +65,65 @@ var _ = Describe("UserStore", Ordered, func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(user2.ID).To(Equal(user1.ID))
 		Expect(user2.Email).To(Equal(user1.Email))
-		Expect(user2.HashedPasswordBase64).To(Equal(user1.HashedPasswordBase64))
+		Expect(user2.HashedPassword).To(Equal(user1.HashedPassword))
+	})
+
+	It("should record failed signins and reset them", func() {
+		ctx := context.Background()
+		lockout := thresholdLockout(2)
+
+		user, err := userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.IsLocked(time.Now())).To(BeFalse())
+
+		user, err = userStore.ClaimSigninAttempt(ctx, user.ID, lockout, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.FailedSigninAttempts).To(Equal(1))
+		Expect(user.LastFailedSigninAt).NotTo(BeNil())
+		Expect(user.IsLocked(time.Now())).To(BeFalse())
+
+		user, err = userStore.ClaimSigninAttempt(ctx, user.ID, lockout, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.FailedSigninAttempts).To(Equal(2))
+		Expect(user.IsLocked(time.Now())).To(BeTrue())
+
+		_, err = userStore.ClaimSigninAttempt(ctx, user.ID, lockout, time.Now())
+		Expect(err).To(MatchError(store.ErrUserLocked))
+
+		user, err = userStore.ResetFailedSignins(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.FailedSigninAttempts).To(Equal(0))
+		Expect(user.LockedUntil).To(BeNil())
+		Expect(user.IsLocked(time.Now())).To(BeFalse())
+	})
+
+	It("should lock out concurrent signin attempts at the threshold", func() {
+		ctx := context.Background()
+		user, err := userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := userStore.ClaimSigninAttempt(ctx, user.ID, thresholdLockout(3), time.Now())
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		claimed := 0
+		for err := range errs {
+			if err == nil {
+				claimed++
+				continue
+			}
+			Expect(err).To(MatchError(store.ErrUserLocked))
+		}
+		Expect(claimed).To(Equal(3))
 	})
 
 	Context("when a user exists", func() {
@@ -85,4 +146,157 @@ var _ = Describe("UserStore", Ordered, func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	It("should treat emails case-insensitively", func() {
+		ctx := context.Background()
+		user, err := userStore.CreateUser(ctx, "Test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+
+		found, err := userStore.ByEmail(ctx, "test@TESTING.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.ID).To(Equal(user.ID))
+		Expect(found.Email).To(Equal("Test@testing.com"))
+
+		_, err = userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should move accounts with colliding emails aside when migrating", func() {
+		ctx := context.Background()
+		Expect(env.MigrateDB(18)).To(Succeed())
+
+		var keptID, movedID uuid.UUID
+		const insert = `INSERT INTO users (email, hashed_password, created_at) VALUES ($1, '', $2) RETURNING id`
+		Expect(env.DB.QueryRowContext(ctx, insert, "Bob@Example.com", time.Now().Add(-time.Hour)).Scan(&keptID)).To(Succeed())
+		Expect(env.DB.QueryRowContext(ctx, insert, "bob@example.com", time.Now()).Scan(&movedID)).To(Succeed())
+		Expect(env.SetupDB()).To(Succeed())
+
+		kept, err := userStore.ByEmail(ctx, "BOB@example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kept.ID).To(Equal(keptID))
+		Expect(kept.Email).To(Equal("Bob@example.com"))
+
+		moved, err := userStore.ByID(ctx, movedID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(moved.Email).To(Equal(movedID.String() + "@duplicate.invalid"))
+
+		var collisionKeptID uuid.UUID
+		var email string
+		const collision = `SELECT kept_user_id, email FROM user_email_collisions WHERE user_id = $1`
+		Expect(env.DB.QueryRowContext(ctx, collision, movedID).Scan(&collisionKeptID, &email)).To(Succeed())
+		Expect(collisionKeptID).To(Equal(keptID))
+		Expect(email).To(Equal("bob@example.com"))
+	})
+
+	It("should update a user's password", func() {
+		ctx := context.Background()
+		user, err := userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+
+		user, err = userStore.UpdatePassword(ctx, user.ID, "newpassword")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.ComparePassword("newpassword")).To(Succeed())
+		Expect(user.ComparePassword("testingpassword")).NotTo(Succeed())
+	})
+
+	It("should update a user's profile", func() {
+		ctx := context.Background()
+		user, err := userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Name).To(BeEmpty())
+
+		user, err = userStore.UpdateProfile(ctx, user.ID, "Test User")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Name).To(Equal("Test User"))
+	})
+
+	It("should enable TOTP and reject replayed steps", func() {
+		ctx := context.Background()
+		user, err := userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+
+		user, err = userStore.SetPendingTOTPSecret(ctx, user.ID, "SECRET")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.IsTOTPEnabled()).To(BeFalse())
+
+		user, err = userStore.EnableTOTP(ctx, user.ID, 100)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.IsTOTPEnabled()).To(BeTrue())
+
+		_, err = userStore.SetPendingTOTPSecret(ctx, user.ID, "OTHER")
+		Expect(err).To(MatchError(sql.ErrNoRows))
+
+		_, err = userStore.UseTOTPStep(ctx, user.ID, 100)
+		Expect(err).To(MatchError(sql.ErrNoRows))
+		_, err = userStore.UseTOTPStep(ctx, user.ID, 101)
+		Expect(err).NotTo(HaveOccurred())
+
+		user, err = userStore.DisableTOTP(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.IsTOTPEnabled()).To(BeFalse())
+	})
+
+	It("should search users by email or name", func() {
+		ctx := context.Background()
+		alice, err := userStore.CreateUser(ctx, "alice@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = userStore.UpdateProfile(ctx, alice.ID, "Alice 100%")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = userStore.CreateUser(ctx, "bob@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+
+		users, err := userStore.Search(ctx, "", 10, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(2))
+
+		users, err = userStore.Search(ctx, "ALICE", 10, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(1))
+		Expect(users[0].ID).To(Equal(alice.ID))
+
+		users, err = userStore.Search(ctx, "100%", 10, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(1))
+
+		// Wildcards in the query are matched literally.
+		users, err = userStore.Search(ctx, "%", 10, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(1))
+
+		users, err = userStore.Search(ctx, "testing.com", 1, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(users).To(HaveLen(1))
+		Expect(users[0].ID).To(Equal(alice.ID))
+	})
+
+	It("should disable and enable a user", func() {
+		ctx := context.Background()
+		user, err := userStore.CreateUser(ctx, "test@testing.com", "testingpassword")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.IsDisabled()).To(BeFalse())
+
+		user, err = userStore.Disable(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.IsDisabled()).To(BeTrue())
+		disabledAt := *user.DisabledAt
+
+		user, err = userStore.Disable(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*user.DisabledAt).To(Equal(disabledAt))
+
+		user, err = userStore.Enable(ctx, user.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.IsDisabled()).To(BeFalse())
+	})
 })
+
+// thresholdLockout locks users for a minute once they reach its number of
+// failed signins.
+type thresholdLockout int
+
+func (l thresholdLockout) LockedUntil(failedAttempts int, now time.Time) time.Time {
+	if failedAttempts >= int(l) {
+		return now.Add(time.Minute)
+	}
+	return now
+}
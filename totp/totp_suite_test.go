@@ -0,0 +1,13 @@
+package totp_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTotp(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Totp Suite")
+}
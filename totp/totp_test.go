@@ -0,0 +1,58 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/talvor/asyncapi/totp"
+)
+
+var _ = Describe("TOTP", func() {
+	// The SHA1 secret of the RFC 6238 test vectors.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	DescribeTable("should match the RFC 6238 test vectors",
+		func(unix int64, code string) {
+			got, err := totp.Code(secret, totp.Step(time.Unix(unix, 0)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(code))
+		},
+		// The RFC lists 8 digit codes, these are their last 6 digits.
+		Entry("59", int64(59), "287082"),
+		Entry("1111111109", int64(1111111109), "081804"),
+		Entry("1111111111", int64(1111111111), "050471"),
+		Entry("1234567890", int64(1234567890), "005924"),
+		Entry("2000000000", int64(2000000000), "279037"),
+	)
+
+	It("should validate codes within the allowed skew", func() {
+		now := time.Unix(1111111111, 0)
+		previous, err := totp.Code(secret, totp.Step(now)-1)
+		Expect(err).NotTo(HaveOccurred())
+
+		step, ok, err := totp.Validate(secret, previous, now, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(step).To(Equal(totp.Step(now) - 1))
+
+		_, ok, err = totp.Validate(secret, previous, now, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should build an otpauth URI", func() {
+		secret, err := totp.GenerateSecret()
+		Expect(err).NotTo(HaveOccurred())
+
+		uri, err := url.Parse(totp.URI("asyncapi", "test@testing.com", secret))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(uri.Scheme).To(Equal("otpauth"))
+		Expect(uri.Host).To(Equal("totp"))
+		Expect(uri.Path).To(Equal("/asyncapi:test@testing.com"))
+		Expect(uri.Query().Get("secret")).To(Equal(secret))
+		Expect(uri.Query().Get("issuer")).To(Equal("asyncapi"))
+	})
+})
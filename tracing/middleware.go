@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span for every request, continuing any trace
+// propagated in the request headers, and stores the span context in the
+// request's user context. Handlers must pass c.UserContext() downstream for
+// their spans to join the trace. It must be installed after the requestid
+// middleware so the request ID can be attached to the span.
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		headers := propagation.HeaderCarrier{}
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			headers.Set(string(key), string(value))
+		})
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headers)
+
+		ctx, span := Tracer().Start(ctx, c.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Method()),
+				semconv.URLPath(c.Path()),
+				semconv.UserAgentOriginal(c.Get(fiber.HeaderUserAgent)),
+				semconv.ClientAddress(c.IP()),
+			),
+		)
+		defer span.End()
+
+		if requestID, ok := c.Locals("requestid").(string); ok {
+			span.SetAttributes(attribute.String("request.id", requestID))
+		}
+
+		c.SetUserContext(ctx)
+		err := c.Next()
+
+		route := c.Route().Path
+		status := c.Response().StatusCode()
+		span.SetName(fmt.Sprintf("%s %s", c.Method(), route))
+		span.SetAttributes(
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(status),
+		)
+		if err != nil {
+			span.RecordError(err)
+		}
+		if status >= http.StatusInternalServerError || err != nil {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		return err
+	}
+}